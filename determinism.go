@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// sliceSink — приёмник, запоминающий все числа с выхода конвейера.
+type sliceSink struct {
+	mu     sync.Mutex
+	values []int64
+}
+
+func (s *sliceSink) Write(v int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = append(s.values, v)
+	return nil
+}
+
+func (s *sliceSink) Close() error { return nil }
+
+// verifyDeterminism запускает конвейер runs раз через pipeline.RunBounded
+// на values чисел без паузы в воркерах и проверяет, что отсортированные
+// выходы всех запусков совпадают с выходом первого. Порядок чисел после
+// сборщика от запуска к запуску разный, но их мультимножество должно быть
+// одним и тем же. Возвращается ошибка с номером первого расходящегося
+// запуска; запуск, остановленный ctx раньше, чем отправлены все числа, тоже
+// считается расхождением. Числа всегда берутся из счётчика RunBounded,
+// поэтому main не допускает -verify-determinism вместе с другими
+// источниками чисел.
+func verifyDeterminism(ctx context.Context, runs, workers int, values int64, opts []pipeline.Option) error {
+	var first []int64
+	for run := 1; run <= runs; run++ {
+		sink := &sliceSink{}
+		runOpts := append(slices.Clip(opts), pipeline.WithDelay(0), pipeline.WithSink(sink))
+		result, err := pipeline.RunBounded(ctx, workers, values, runOpts...)
+		if err != nil {
+			return fmt.Errorf("запуск %d: %w", run, err)
+		}
+		if err := result.Verify(); err != nil {
+			return fmt.Errorf("запуск %d: %w", run, err)
+		}
+		if result.StopReason != pipeline.StopCount {
+			return fmt.Errorf("запуск %d остановлен до отправки всех чисел (%s): отправлено %d из %d",
+				run, result.StopCause, result.InputCount, values)
+		}
+
+		slices.Sort(sink.values)
+		if run == 1 {
+			first = sink.values
+			continue
+		}
+		if i := firstDifference(first, sink.values); i >= 0 {
+			return fmt.Errorf("запуск %d расходится с первым: позиция %d: %s != %s",
+				run, i, valueAt(first, i), valueAt(sink.values, i))
+		}
+	}
+	return nil
+}
+
+// firstDifference возвращает первую позицию, в которой a и b различаются,
+// или -1, если они равны.
+func firstDifference(a, b []int64) int {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+// valueAt возвращает s[i] строкой или "<нет>", если такой позиции нет.
+func valueAt(s []int64, i int) string {
+	if i < len(s) {
+		return fmt.Sprint(s[i])
+	}
+	return "<нет>"
+}
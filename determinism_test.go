@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+var errOne = errors.New("единица отбракована")
+
+func TestVerifyDeterminism(t *testing.T) {
+	if err := verifyDeterminism(context.Background(), 5, 4, 2000, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyDeterminismReportsDivergentRun(t *testing.T) {
+	const values = 500
+	// начиная со второго запуска число 1 отбраковывается, и выход
+	// отличается от первого
+	var calls atomic.Int64
+	transform := func(v int64) (int64, error) {
+		if calls.Add(1) > values && v == 1 {
+			return 0, errOne
+		}
+		return v, nil
+	}
+	err := verifyDeterminism(context.Background(), 5, 4, values, []pipeline.Option{pipeline.WithTransform(transform)})
+	if err == nil || !strings.HasPrefix(err.Error(), "запуск 2 расходится с первым") {
+		t.Fatalf("получено %v, ожидалось расхождение запуска 2", err)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		a, b []int64
+		want int
+	}{
+		{[]int64{1, 2, 3}, []int64{1, 2, 3}, -1},
+		{[]int64{1, 2, 3}, []int64{1, 5, 3}, 1},
+		{[]int64{1, 2}, []int64{1, 2, 3}, 2},
+		{nil, nil, -1},
+	}
+	for _, tt := range tests {
+		if got := firstDifference(tt.a, tt.b); got != tt.want {
+			t.Errorf("firstDifference(%v, %v) = %d, ожидалось %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
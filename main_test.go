@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// inconsistent — итоги с расхождением между входом и выходом.
+var inconsistent = pipeline.Result{
+	InputCount:  10,
+	InputSum:    55,
+	OutputCount: 9,
+	OutputSum:   45,
+	PerChannel:  []int64{5, 4},
+}
+
+func TestReportQuietText(t *testing.T) {
+	var stdout bytes.Buffer
+	cfg := config{output: outputText, labels: pipeline.LabelsRU, quiet: true}
+	// run возвращает ошибку report, а main завершает программу по ошибке
+	// с ненулевым кодом
+	if err := report(&stdout, cfg, inconsistent); err == nil {
+		t.Fatal("расхождение итогов не обнаружено при -quiet")
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("при -quiet в stdout выведено %q", stdout.String())
+	}
+}
+
+func TestReportQuietJSON(t *testing.T) {
+	var stdout bytes.Buffer
+	cfg := config{output: outputJSON, quiet: true}
+	consistent := pipeline.Result{InputCount: 3, InputSum: 6, OutputCount: 3, OutputSum: 6, PerChannel: []int64{3}}
+	if err := report(&stdout, cfg, consistent); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout.String(), "{") {
+		t.Fatalf("при -quiet -output json выведено %q, ожидался JSON", stdout.String())
+	}
+}
+
+func TestDeadlineContextCause(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config
+	}{
+		{"duration", config{duration: 10 * time.Millisecond}},
+		{"deadline", config{deadline: time.Now().Add(10 * time.Millisecond)}},
+		{"прошедший deadline", config{deadline: time.Now().Add(-time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := deadlineContext(context.Background(), tt.cfg)
+			defer cancel()
+			result, err := pipeline.Run(ctx, 2, pipeline.WithDelay(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cause := context.Cause(ctx); !errors.Is(cause, pipeline.ErrDeadline) {
+				t.Fatalf("context.Cause = %v, ожидалась ErrDeadline", cause)
+			}
+			if result.StopCause != "deadline" {
+				t.Fatalf("StopCause = %q, ожидалось deadline", result.StopCause)
+			}
+		})
+	}
+}
+
+// runToJSON запускает run с cfg, выводя итоги в JSON во временный файл, и
+// возвращает их.
+func runToJSON(t *testing.T, cfg config) pipeline.Result {
+	t.Helper()
+	cfg.output = outputJSON
+	cfg.outputFile = filepath.Join(t.TempDir(), "result.json")
+	if cfg.workers == 0 {
+		cfg.workers = 3
+	}
+	cfg.logLevel = slog.LevelError // журнал run пишет в stderr
+	t.Cleanup(func() { pipeline.SetLogger(nil) })
+	if err := run(cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := pipeline.ParseResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRunValuesExactCount(t *testing.T) {
+	result := runToJSON(t, config{values: 1000, duration: 10 * time.Second})
+	if result.InputCount != 1000 || result.OutputCount != 1000 {
+		t.Fatalf("отправлено %d, доставлено %d, ожидалось по 1000", result.InputCount, result.OutputCount)
+	}
+	if result.StopCause != "count" {
+		t.Fatalf("StopCause = %q, ожидалось count", result.StopCause)
+	}
+}
+
+func TestRunValuesDurationFirst(t *testing.T) {
+	result := runToJSON(t, config{values: 1 << 40, duration: 20 * time.Millisecond})
+	if result.InputCount >= 1<<40 {
+		t.Fatalf("отправлено %d чисел до таймаута", result.InputCount)
+	}
+	if result.StopCause != "deadline" {
+		t.Fatalf("StopCause = %q, ожидалось deadline", result.StopCause)
+	}
+}
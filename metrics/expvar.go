@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+	"sync"
+)
+
+// Переменные expvar, общие для всех наблюдателей Expvar: expvar публикует
+// переменные глобально и не допускает повторной регистрации имени.
+var (
+	expvarOnce       sync.Once
+	expvarGenerated  *expvar.Int
+	expvarProcessed  *expvar.Int
+	expvarPerChannel *expvar.Map
+)
+
+// Expvar — наблюдатель конвейера, публикующий счётчики через стандартный
+// пакет expvar: pipeline.generated, pipeline.processed и карту
+// pipeline.per_channel с количеством чисел по индексу воркера. Переменные
+// доступны через expvar.Handler, по умолчанию на /debug/vars.
+type Expvar struct{}
+
+// NewExpvar публикует переменные expvar при первом вызове и возвращает
+// наблюдатель, обновляющий их. Все наблюдатели Expvar процесса обновляют
+// одни и те же переменные.
+func NewExpvar() *Expvar {
+	expvarOnce.Do(func() {
+		expvarGenerated = expvar.NewInt("pipeline.generated")
+		expvarProcessed = expvar.NewInt("pipeline.processed")
+		expvarPerChannel = expvar.NewMap("pipeline.per_channel")
+	})
+	return &Expvar{}
+}
+
+// Generated увеличивает pipeline.generated.
+func (*Expvar) Generated(int64) {
+	expvarGenerated.Add(1)
+}
+
+// Processed увеличивает pipeline.processed и счётчик воркера worker в
+// pipeline.per_channel.
+func (*Expvar) Processed(worker int, _ int64) {
+	expvarProcessed.Add(1)
+	expvarPerChannel.Add(strconv.Itoa(worker), 1)
+}
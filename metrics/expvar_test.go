@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// expvarInt возвращает значение опубликованной переменной expvar.Int name.
+func expvarInt(t *testing.T, name string) int64 {
+	t.Helper()
+	v, ok := expvar.Get(name).(*expvar.Int)
+	if !ok {
+		t.Fatalf("переменная %s не опубликована как expvar.Int", name)
+	}
+	return v.Value()
+}
+
+// readPerChannel возвращает счётчики воркеров из pipeline.per_channel.
+func readPerChannel(t *testing.T, workers int) []int64 {
+	t.Helper()
+	m, ok := expvar.Get("pipeline.per_channel").(*expvar.Map)
+	if !ok {
+		t.Fatal("переменная pipeline.per_channel не опубликована как expvar.Map")
+	}
+	counts := make([]int64, workers)
+	for i := range counts {
+		if v, ok := m.Get(strconv.Itoa(i)).(*expvar.Int); ok {
+			counts[i] = v.Value()
+		}
+	}
+	return counts
+}
+
+func TestExpvarPublishesCounters(t *testing.T) {
+	const workers = 3
+	observer := NewExpvar()
+	// переменные общие для процесса, поэтому сравниваются приращения
+	generated := expvarInt(t, "pipeline.generated")
+	processed := expvarInt(t, "pipeline.processed")
+	perChannel := readPerChannel(t, workers)
+
+	result, err := pipeline.RunBounded(context.Background(), workers, 300,
+		pipeline.WithDelay(0), pipeline.WithObserver(observer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expvarInt(t, "pipeline.generated") - generated; got != result.InputCount {
+		t.Fatalf("pipeline.generated увеличилась на %d, ожидалось %d", got, result.InputCount)
+	}
+	if got := expvarInt(t, "pipeline.processed") - processed; got != result.OutputCount {
+		t.Fatalf("pipeline.processed увеличилась на %d, ожидалось %d", got, result.OutputCount)
+	}
+	after := readPerChannel(t, workers)
+	for i := range after {
+		if got := after[i] - perChannel[i]; got != result.PerChannel[i] {
+			t.Fatalf("счётчик воркера %d увеличился на %d, ожидалось %d", i, got, result.PerChannel[i])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package metrics содержит реализации pipeline.Observer, публикующие
+// счётчики конвейера во внешние системы мониторинга.
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus — наблюдатель конвейера, обновляющий счётчики Prometheus:
+// pipeline_generated_total, pipeline_processed_total и
+// pipeline_worker_processed_total{worker="i"}.
+type Prometheus struct {
+	generated prometheus.Counter
+	processed prometheus.Counter
+	perWorker *prometheus.CounterVec
+}
+
+// NewPrometheus создаёт счётчики и регистрирует их в reg.
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	p := &Prometheus{
+		generated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pipeline_generated_total",
+			Help: "Количество чисел, отправленных генератором.",
+		}),
+		processed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pipeline_processed_total",
+			Help: "Количество чисел, дошедших до результирующего канала.",
+		}),
+		perWorker: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pipeline_worker_processed_total",
+			Help: "Количество чисел, прошедших через канал воркера.",
+		}, []string{"worker"}),
+	}
+	for _, c := range []prometheus.Collector{p.generated, p.processed, p.perWorker} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Generated увеличивает pipeline_generated_total.
+func (p *Prometheus) Generated(int64) {
+	p.generated.Inc()
+}
+
+// Processed увеличивает pipeline_processed_total и счётчик воркера worker.
+func (p *Prometheus) Processed(worker int, _ int64) {
+	p.processed.Inc()
+	p.perWorker.WithLabelValues(strconv.Itoa(worker)).Inc()
+}
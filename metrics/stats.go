@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// Stats — наблюдатель конвейера, который хранит счётчики в памяти процесса,
+// чтобы их можно было читать из других горутин без Prometheus и HTTP.
+// Подключается через pipeline.WithObserver.
+type Stats struct {
+	generatedCount int64
+	generatedSum   int64
+	processedCount int64
+	processedSum   int64
+	perWorker      []int64
+}
+
+// NewStats создаёт Stats для конвейера из workers воркеров. Числа воркеров с
+// индексом вне [0, workers) учитываются только в общих итогах.
+func NewStats(workers int) *Stats {
+	return &Stats{perWorker: make([]int64, max(workers, 0))}
+}
+
+// Generated учитывает число, отправленное генератором.
+func (s *Stats) Generated(v int64) {
+	atomic.AddInt64(&s.generatedCount, 1)
+	atomic.AddInt64(&s.generatedSum, v)
+}
+
+// Processed учитывает число, пересланное из канала воркера worker в
+// результирующий канал.
+func (s *Stats) Processed(worker int, v int64) {
+	atomic.AddInt64(&s.processedCount, 1)
+	atomic.AddInt64(&s.processedSum, v)
+	if worker >= 0 && worker < len(s.perWorker) {
+		atomic.AddInt64(&s.perWorker[worker], 1)
+	}
+}
+
+// Snapshot возвращает текущие значения счётчиков. Snapshot можно вызывать в
+// любой момент, в том числе во время работы конвейера: каждый счётчик
+// читается атомарно, но вместе они не образуют согласованного среза, так
+// что посреди работы OutputCount может отставать от InputCount. Min и Max в
+// снимке не заполняются.
+func (s *Stats) Snapshot() pipeline.Result {
+	perChannel := make([]int64, len(s.perWorker))
+	for i := range s.perWorker {
+		perChannel[i] = atomic.LoadInt64(&s.perWorker[i])
+	}
+	r := pipeline.Result{
+		InputCount:  atomic.LoadInt64(&s.generatedCount),
+		InputSum:    atomic.LoadInt64(&s.generatedSum),
+		OutputCount: atomic.LoadInt64(&s.processedCount),
+		OutputSum:   atomic.LoadInt64(&s.processedSum),
+		PerChannel:  perChannel,
+	}
+	r.Empty = r.OutputCount == 0
+	return r
+}
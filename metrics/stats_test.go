@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// Snapshot читают во время работы конвейера; под -race это проверяет, что
+// все счётчики Stats читаются и пишутся атомарно.
+func TestStatsSnapshotDuringRun(t *testing.T) {
+	const workers = 4
+	stats := NewStats(workers)
+	done := make(chan struct{})
+	snapshots := make(chan int)
+	go func() {
+		n := 0
+		var last pipeline.Result
+		for {
+			select {
+			case <-done:
+				snapshots <- n
+				return
+			default:
+			}
+			s := stats.Snapshot()
+			if s.InputCount < last.InputCount || s.OutputCount < last.OutputCount {
+				t.Errorf("счётчики уменьшились: %+v после %+v", s, last)
+			}
+			last = s
+			n++
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+
+	result, err := pipeline.RunBounded(context.Background(), workers, 20000,
+		pipeline.WithDelay(0), pipeline.WithObserver(stats))
+	close(done)
+	if n := <-snapshots; n == 0 {
+		t.Fatal("ни одного снимка за время работы")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := stats.Snapshot()
+	if s.InputCount != result.InputCount || s.InputSum != result.InputSum ||
+		s.OutputCount != result.OutputCount || s.OutputSum != result.OutputSum {
+		t.Fatalf("снимок %+v не совпадает с итогами %+v", s, result)
+	}
+	if !slices.Equal(s.PerChannel, result.PerChannel) {
+		t.Fatalf("разбивка снимка %v, итогов %v", s.PerChannel, result.PerChannel)
+	}
+}
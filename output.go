@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// Форматы вывода итогов, допустимые значения флага -output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCSV  = "csv"
+
+	// outputJSONPretty — JSON с отступами; выбирается флагом -pretty
+	// вместе с -output json, а не значением -output
+	outputJSONPretty = "json-pretty"
+)
+
+// locales — подписи текстовых итогов для значений флага -locale.
+var locales = map[string]pipeline.Labels{
+	"ru": pipeline.LabelsRU,
+	"en": pipeline.LabelsEN,
+}
+
+// writeResult выводит итоги result в w в формате format. Подписи labels
+// используются только в текстовом формате.
+func writeResult(w io.Writer, format string, labels pipeline.Labels, result pipeline.Result) error {
+	switch format {
+	case outputText:
+		return writeText(w, labels, result)
+	case outputJSON:
+		return writeJSON(w, result, false)
+	case outputJSONPretty:
+		return writeJSON(w, result, true)
+	case outputCSV:
+		return writeCSV(w, result)
+	default:
+		return fmt.Errorf("неизвестный формат вывода %q", format)
+	}
+}
+
+// writeText выводит итоги в виде строк для человека с подписями
+// labels (см. pipeline.Result.Text).
+func writeText(w io.Writer, labels pipeline.Labels, result pipeline.Result) error {
+	_, err := fmt.Fprintln(w, result.Text(labels))
+	return err
+}
+
+// writeJSON выводит итоги одной строкой JSON (см. pipeline.Result.JSON)
+// или, если pretty, в несколько строк с отступом в два пробела.
+func writeJSON(w io.Writer, result pipeline.Result, pretty bool) error {
+	data, err := result.JSON()
+	if err != nil {
+		return err
+	}
+	if pretty {
+		var b bytes.Buffer
+		if err := json.Indent(&b, data, "", "  "); err != nil {
+			return err
+		}
+		data = b.Bytes()
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeCSV выводит разбивку по каналам в виде CSV с колонками channel и
+// count: строка на каждый канал и строка total с их суммой.
+func writeCSV(w io.Writer, result pipeline.Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"channel", "count"}); err != nil {
+		return err
+	}
+	var total int64
+	for i, v := range result.PerChannel {
+		total += v
+		if err := cw.Write([]string{strconv.Itoa(i), strconv.FormatInt(v, 10)}); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write([]string{"total", strconv.FormatInt(total, 10)}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeBalance выводит оценку равномерности разбивки по каналам: индекс
+// справедливости Джейна и статистику хи-квадрат против равномерного
+// распределения с ориентиром для случайного распределения (см.
+// pipeline.Result.ChiSquareUniform).
+func writeBalance(w io.Writer, result pipeline.Result) {
+	stat, dof := result.ChiSquareUniform()
+	fmt.Fprintf(w, "Индекс Джейна: %.4f\n", result.Fairness())
+	fmt.Fprintf(w, "Хи-квадрат: %.2f, степеней свободы %d; для случайного равномерного распределения в среднем %d, перекос — больше %.2f\n",
+		stat, dof, dof, float64(dof)+3*math.Sqrt(2*float64(dof)))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+func TestWriteCSV(t *testing.T) {
+	result := pipeline.Result{PerChannel: []int64{3, 5, 2}}
+	var b bytes.Buffer
+	if err := writeCSV(&b, result); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("вывод не разбирается как CSV: %v", err)
+	}
+	want := [][]string{
+		{"channel", "count"},
+		{"0", "3"},
+		{"1", "5"},
+		{"2", "2"},
+		{"total", "10"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal[[]string]) {
+		t.Fatalf("получено %q, ожидалось %q", rows, want)
+	}
+}
+
+func TestWriteJSONPretty(t *testing.T) {
+	result := pipeline.Result{InputSum: 10, InputCount: 4, OutputSum: 10, OutputCount: 4, PerChannel: []int64{3, 1}}
+	var b bytes.Buffer
+	if err := writeResult(&b, outputFormat(outputJSON, true), pipeline.LabelsRU, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.TrimSpace(b.String()), "\n") {
+		t.Fatalf("вывод -pretty в одну строку: %s", b.String())
+	}
+	if !strings.Contains(b.String(), "\n  \"") {
+		t.Fatalf("поля не выделены отступом в два пробела: %s", b.String())
+	}
+	parsed, err := pipeline.ParseResult(b.Bytes())
+	if err != nil {
+		t.Fatalf("вывод -pretty не разбирается: %v", err)
+	}
+	if !parsed.Equal(result) {
+		t.Fatalf("после разбора получено %+v, ожидалось %+v", parsed, result)
+	}
+}
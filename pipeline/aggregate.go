@@ -0,0 +1,88 @@
+package pipeline
+
+// Aggregate читает канал in до закрытия и возвращает количество и сумму
+// прочитанных чисел. Для закрытого пустого канала возвращается (0, 0).
+//
+// Aggregate выполняет на выходе конвейера тот же подсчёт, что функция fn
+// делает на входе, поэтому итоги по обе стороны можно сравнивать напрямую.
+func Aggregate(in <-chan int64) (count int64, sum int64) {
+	for v := range in {
+		count++
+		sum += v
+	}
+	return count, sum
+}
+
+// Collect читает канал in до закрытия, сворачивая числа функцией reduce,
+// начиная с initial, и возвращает накопленное значение. Порядок чисел на
+// выходе конвейера не определён, поэтому результат не должен зависеть от
+// порядка (сумма, произведение, множество различных чисел подходят).
+// Например, Aggregate — это Collect, накапливающий количество и сумму.
+func Collect[R any](in <-chan int64, initial R, reduce func(R, int64) R) R {
+	acc := initial
+	for v := range in {
+		acc = reduce(acc, v)
+	}
+	return acc
+}
+
+// Stats — итоги чтения канала функцией AggregateStats.
+type Stats struct {
+	Count int64
+	Sum   int64
+	Min   int64 // наименьшее число; 0, если Empty
+	Max   int64 // наибольшее число; 0, если Empty
+	Empty bool  // true, если канал закрылся, не передав ни одного числа
+
+	// Overflow — сумма хотя бы раз вышла за пределы int64; Sum тогда равна
+	// истинной сумме по модулю 2^64
+	Overflow bool
+}
+
+// AggregateStats — вариант Aggregate, который кроме количества и суммы
+// отслеживает наименьшее и наибольшее прочитанные числа. Для пустого канала
+// Min и Max равны нулю, а Empty — true, чтобы отличать отсутствие данных от
+// минимума 0.
+func AggregateStats(in <-chan int64) Stats {
+	s := Stats{Empty: true}
+	for v := range in {
+		s.add(v)
+	}
+	return s
+}
+
+// add учитывает число v в итогах. Итоги должны начинаться с Empty: true.
+func (s *Stats) add(v int64) {
+	if s.Empty || v < s.Min {
+		s.Min = v
+	}
+	if s.Empty || v > s.Max {
+		s.Max = v
+	}
+	s.Empty = false
+	s.Count++
+	if addOverflows(s.Sum, v) {
+		s.Overflow = true
+	}
+	s.Sum += v
+}
+
+// AggregateFloat — вариант Aggregate для чисел float64.
+//
+// Сумма float64 зависит от порядка сложения, а порядок чисел после FanIn
+// не сохраняется, поэтому суммы на входе и выходе совпадают точно только
+// для точно представимых значений (например, целых до 2^53 или степеней
+// двойки).
+func AggregateFloat(in <-chan float64) (count int64, sum float64) {
+	for v := range in {
+		count++
+		sum += v
+	}
+	return count, sum
+}
+
+// addOverflows сообщает, выходит ли a+b за пределы int64.
+func addOverflows(a, b int64) bool {
+	sum := a + b
+	return (b > 0 && sum < a) || (b < 0 && sum > a)
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestGeneratorFloatSequence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var generated float64
+	ch := make(chan float64)
+	go GeneratorFloat(ctx, ch, func(f float64) { generated += f })
+	var received float64
+	for want := 1.0; want <= 100; want++ {
+		got := <-ch
+		if got != want {
+			t.Fatalf("получено %v, ожидалось %v", got, want)
+		}
+		received += got
+	}
+	cancel()
+	for f := range ch {
+		received += f
+	}
+	if generated != received {
+		t.Fatalf("fn насчитала %v, получено %v", generated, received)
+	}
+}
+
+// Степени двойки складываются в float64 точно в любом порядке, так что
+// сумма после нескольких WorkerFloat не зависит от того, как воркеры
+// перемешали числа.
+func TestFloatPipelinePowersOfTwo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 53
+	in := make(chan float64)
+	go func() {
+		defer close(in)
+		for i := 0; i < n; i++ {
+			in <- math.Ldexp(1, -i)
+		}
+	}()
+
+	out := make(chan float64)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		ch := make(chan float64)
+		go WorkerFloat(ctx, in, ch)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range ch {
+				out <- f
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	count, sum := AggregateFloat(out)
+	if count != n {
+		t.Fatalf("получено %d чисел, ожидалось %d", count, n)
+	}
+	if want := 2 - math.Ldexp(1, -(n-1)); sum != want {
+		t.Fatalf("сумма %v, ожидалось %v", sum, want)
+	}
+}
+
+func TestAggregateStatsMinMax(t *testing.T) {
+	s := AggregateStats(values(7, -3, 12, 0, 5))
+	want := Stats{Count: 5, Sum: 21, Min: -3, Max: 12}
+	if s != want {
+		t.Fatalf("получено %+v, ожидалось %+v", s, want)
+	}
+
+	empty := AggregateStats(values())
+	if empty != (Stats{Empty: true}) {
+		t.Fatalf("для пустого канала получено %+v", empty)
+	}
+	zero := AggregateStats(values(0))
+	if zero.Empty || zero.Min != 0 || zero.Max != 0 {
+		t.Fatalf("для канала с нулём получено %+v", zero)
+	}
+}
+
+func TestRunReportsMinMax(t *testing.T) {
+	result, err := RunBounded(context.Background(), 3, 100, WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Empty || result.Min != 1 || result.Max != 100 {
+		t.Fatalf("Min %d, Max %d, Empty %v, ожидалось 1, 100, false", result.Min, result.Max, result.Empty)
+	}
+}
+
+func TestCollectSum(t *testing.T) {
+	sum := Collect(values(1, 2, 3, 4), int64(0), func(acc, v int64) int64 { return acc + v })
+	if sum != 10 {
+		t.Fatalf("сумма %d, ожидалось 10", sum)
+	}
+	if empty := Collect(values(), int64(7), func(acc, v int64) int64 { return acc + v }); empty != 7 {
+		t.Fatalf("для пустого канала получено %d, ожидалось начальное 7", empty)
+	}
+}
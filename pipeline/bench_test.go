@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchmarkRun прогоняет через RunBounded b.N чисел и сообщает пропускную
+// способность в числах в секунду.
+func benchmarkRun(b *testing.B, numOut int, opts ...Option) {
+	b.Helper()
+	b.ReportAllocs()
+	result, err := RunBounded(context.Background(), numOut, int64(b.N), opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if result.InputCount != int64(b.N) {
+		b.Fatalf("отправлено %d чисел, ожидалось %d", result.InputCount, b.N)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "values/s")
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, numOut := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", numOut), func(b *testing.B) {
+			benchmarkRun(b, numOut, WithDelay(0))
+		})
+	}
+}
+
+// BenchmarkWorkerWithDelay пропускает b.N чисел через один воркер и
+// показывает, во что обходится пауза после каждой пересылки.
+func BenchmarkWorkerWithDelay(b *testing.B) {
+	for _, delay := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("delay=%v", delay), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			in := make(chan int64)
+			out := make(chan int64)
+			go WorkerWithDelay(ctx, in, out, delay)
+			go func() {
+				defer close(in)
+				for v := int64(1); v <= int64(b.N); v++ {
+					in <- v
+				}
+			}()
+			b.ResetTimer()
+			for range out {
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "values/s")
+		})
+	}
+}
+
+func BenchmarkInputBuffer(b *testing.B) {
+	for _, size := range []int{0, 1, 16, 256} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			benchmarkRun(b, 4, WithDelay(0), WithInputBuffer(size))
+		})
+	}
+}
+
+func BenchmarkOutputBuffer(b *testing.B) {
+	const numOut = 4
+	for _, size := range []int{0, numOut, 1024} {
+		b.Run(fmt.Sprintf("outbuf=%d", size), func(b *testing.B) {
+			benchmarkRun(b, numOut, WithDelay(0), WithOutputBuffer(size))
+		})
+	}
+}
+
+// BenchmarkLockThreads сравнивает воркеры, закреплённые за потоками ОС, с
+// обычными при количестве воркеров, равном GOMAXPROCS.
+func BenchmarkLockThreads(b *testing.B) {
+	numOut := runtime.GOMAXPROCS(0)
+	b.Run("unlocked", func(b *testing.B) {
+		benchmarkRun(b, numOut, WithDelay(0))
+	})
+	b.Run("locked", func(b *testing.B) {
+		benchmarkRun(b, numOut, WithDelay(0), WithLockThreads())
+	})
+}
+
+// BenchmarkGeneratedCounter сравнивает подсчёт сгенерированных чисел
+// атомарными сложениями на каждое число с CoalescingCounter при 16
+// воркерах.
+func BenchmarkGeneratedCounter(b *testing.B) {
+	const numOut = 16
+	b.Run("atomic", func(b *testing.B) {
+		var count, sum int64
+		benchmarkRun(b, numOut, WithDelay(0), WithObserver(observerFunc(func(v int64) {
+			atomic.AddInt64(&count, 1)
+			atomic.AddInt64(&sum, v)
+		})))
+		if got := atomic.LoadInt64(&count); got != int64(b.N) {
+			b.Fatalf("учтено %d чисел, ожидалось %d", got, b.N)
+		}
+	})
+	b.Run("coalescing", func(b *testing.B) {
+		counter := NewCoalescingCounter(DefaultCoalesce)
+		benchmarkRun(b, numOut, WithDelay(0), WithObserver(observerFunc(counter.Add)))
+		counter.Flush()
+		if count, _ := counter.Load(); count != int64(b.N) {
+			b.Fatalf("учтено %d чисел, ожидалось %d", count, b.N)
+		}
+	})
+}
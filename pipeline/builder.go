@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWorkers — количество воркеров Pipeline по умолчанию.
+const DefaultWorkers = 5
+
+// Pipeline собирает запуск конвейера цепочкой вызовов:
+//
+//	result, err := pipeline.New(ctx).
+//		WithWorkers(8).
+//		WithDelay(0).
+//		Generate(fn).
+//		Collect()
+//
+// Внутри используются те же Generator, воркеры и сборщик, что и в Run, так
+// что за закрытием всех каналов следит сам конвейер.
+type Pipeline struct {
+	ctx     context.Context
+	workers int
+	opts    []Option
+}
+
+// New создаёт Pipeline, генерирующий числа до отмены ctx, с DefaultWorkers
+// воркерами.
+func New(ctx context.Context) *Pipeline {
+	return &Pipeline{ctx: ctx, workers: DefaultWorkers}
+}
+
+// WithWorkers задаёт количество воркеров.
+func (p *Pipeline) WithWorkers(n int) *Pipeline {
+	p.workers = n
+	return p
+}
+
+// WithDelay задаёт паузу воркеров после пересылки каждого числа.
+func (p *Pipeline) WithDelay(d time.Duration) *Pipeline {
+	return p.With(WithDelay(d))
+}
+
+// Generate добавляет функцию fn, которая, как и у Generator, вызывается для
+// каждого сгенерированного числа.
+func (p *Pipeline) Generate(fn func(int64)) *Pipeline {
+	return p.With(WithObserver(observerFunc(fn)))
+}
+
+// With добавляет произвольные параметры запуска.
+func (p *Pipeline) With(opts ...Option) *Pipeline {
+	p.opts = append(p.opts, opts...)
+	return p
+}
+
+// Collect запускает конвейер, дожидается его завершения и возвращает итоги
+// так же, как Run.
+func (p *Pipeline) Collect() (Result, error) {
+	return Run(p.ctx, p.workers, p.opts...)
+}
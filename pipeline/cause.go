@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+)
+
+// Причины остановки генерации, которые передаются в отмену контекста и
+// читаются через context.Cause. ErrOverflow тоже служит такой причиной,
+// когда Run останавливает генератор на переполнении int64.
+var (
+	ErrDeadline    = errors.New("время генерации истекло")
+	ErrSignal      = errors.New("получен сигнал остановки")
+	ErrIdleTimeout = errors.New("генератор простаивает")
+)
+
+// causeName возвращает краткое название причины остановки для
+// Result.StopCause: deadline, signal, idle, overflow, sink, canceled или
+// count, если генератор завершился без отмены.
+func causeName(cause error) string {
+	switch {
+	case cause == nil:
+		return "count"
+	case errors.Is(cause, ErrSignal):
+		return "signal"
+	case errors.Is(cause, ErrIdleTimeout):
+		return "idle"
+	case errors.Is(cause, ErrOverflow):
+		return "overflow"
+	case errors.Is(cause, ErrSink):
+		return "sink"
+	case errors.Is(cause, ErrDeadline), errors.Is(cause, context.DeadlineExceeded):
+		return "deadline"
+	case errors.Is(cause, context.Canceled):
+		return "canceled"
+	default:
+		return cause.Error()
+	}
+}
+
+// stopGenerationKey — ключ функции отмены генерации в контексте генератора.
+type stopGenerationKey struct{}
+
+// stopGeneration отменяет контекст генератора, запущенного RunWith, с
+// причиной cause. Вне RunWith ничего не делает.
+func stopGeneration(ctx context.Context, cause error) {
+	if cancel, ok := ctx.Value(stopGenerationKey{}).(context.CancelCauseFunc); ok {
+		cancel(cause)
+	}
+}
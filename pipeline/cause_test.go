@@ -0,0 +1,28 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCauseName(t *testing.T) {
+	tests := []struct {
+		cause error
+		want  string
+	}{
+		{nil, "count"},
+		{ErrDeadline, "deadline"},
+		{context.DeadlineExceeded, "deadline"},
+		{ErrSignal, "signal"},
+		{ErrIdleTimeout, "idle"},
+		{ErrOverflow, "overflow"},
+		{fmt.Errorf("запись: %w", ErrSink), "sink"},
+		{context.Canceled, "canceled"},
+	}
+	for _, tt := range tests {
+		if got := causeName(tt.cause); got != tt.want {
+			t.Errorf("causeName(%v) = %q, ожидалось %q", tt.cause, got, tt.want)
+		}
+	}
+}
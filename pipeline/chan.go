@@ -0,0 +1,59 @@
+package pipeline
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// sendCtx отправляет v в канал ch и возвращает true, если отправка
+// состоялась, или false, если раньше отменился ctx. Уже отменённый ctx
+// проверяется до отправки, поэтому после отмены в канал не попадает ни
+// одного значения, даже если получатель готов его принять.
+//
+// Если в ctx есть счётчик блокировок (см. withBlocked), время, которое
+// отправка ждала получателя, прибавляется к нему.
+func sendCtx[T any](ctx context.Context, ch chan<- T, v T) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if blocked, ok := ctx.Value(blockedKey{}).(*int64); ok {
+		select {
+		case ch <- v:
+			return true
+		default:
+		}
+		start := clk().Now()
+		defer func() {
+			atomic.AddInt64(blocked, int64(clk().Now().Sub(start)))
+		}()
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- v:
+		return true
+	}
+}
+
+// blockedKey — ключ счётчика блокировок в контексте.
+type blockedKey struct{}
+
+// withBlocked возвращает копию ctx, в которой sendCtx накапливает в
+// *blocked время ожидания получателя в наносекундах; счётчик обновляется
+// атомарно. Так Run измеряет, сколько генератор и сборщик простаивают на
+// отправке, не меняя сигнатур стадий.
+func withBlocked(ctx context.Context, blocked *int64) context.Context {
+	return context.WithValue(ctx, blockedKey{}, blocked)
+}
+
+// recvCtx читает значение из канала ch. Второе значение равно false, если
+// канал закрыт или раньше отменился ctx.
+func recvCtx[T any](ctx context.Context, ch <-chan T) (T, bool) {
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	case v, ok := <-ch:
+		return v, ok
+	}
+}
@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock — источник времени для воркеров и ограничителя скорости генерации.
+// По умолчанию используется настоящее время; в тестах его можно заменить на
+// FakeClock через SetClock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	// NewTicker возвращает тикер с периодом d > 0, как time.NewTicker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker — периодический таймер часов Clock. Как и у time.Ticker, в канале
+// C помещается один тик: тики, которые получатель не успел прочитать,
+// отбрасываются. Stop останавливает тикер, не закрывая канал.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// clockValue хранит текущие часы пакета в обёртке clockHolder, так как
+// atomic.Value требует значений одного конкретного типа.
+var clockValue atomic.Value
+
+type clockHolder struct{ Clock }
+
+func init() {
+	SetClock(nil)
+}
+
+// SetClock задаёт часы, которые используют Worker и его варианты, а также
+// GeneratorRate. При c == nil восстанавливаются настоящие часы. Часы
+// следует менять до запуска конвейера.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clockValue.Store(clockHolder{c})
+}
+
+// clk возвращает текущие часы пакета.
+func clk() Clock {
+	return clockValue.Load().(clockHolder).Clock
+}
+
+// realClock — настоящие часы на основе пакета time.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+// realTicker — Ticker на основе time.Ticker.
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// FakeClock — управляемые вручную часы для тестов. Время стоит на месте,
+// пока его не сдвинет Advance; Sleep и After срабатывают, когда время
+// доходит до их срока, а тикеры — каждый раз, когда оно проходит очередной
+// период.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	tickers []*fakeTicker
+}
+
+// fakeWaiter — ожидание, которое сработает в момент at.
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFakeClock создаёт FakeClock, показывающие время start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now возвращает текущее время часов.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After возвращает канал, в который будет отправлено время часов, когда
+// Advance сдвинет их не меньше чем на d. При d <= 0 канал срабатывает сразу.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep блокируется, пока Advance не сдвинет часы не меньше чем на d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// NewTicker возвращает тикер, срабатывающий, когда Advance доводит часы до
+// очередного кратного d момента после текущего. Если Advance перескакивает
+// несколько периодов сразу, тикер срабатывает один раз. При d <= 0
+// NewTicker паникует, как и time.NewTicker.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("pipeline: неположительный период FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{clock: c, ch: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance сдвигает часы на d и будит все ожидания и тикеры, чей срок
+// наступил.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.ch <- c.now:
+		default: // прошлый тик ещё не прочитан
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Waiters возвращает количество ожиданий Sleep и After, которые ещё не
+// сработали, вместе с количеством работающих тикеров; тест может
+// дождаться, пока воркер уснёт или генератор заведёт тикер, прежде чем
+// вызывать Advance.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters) + len(c.tickers)
+}
+
+// fakeTicker — тикер FakeClock.
+type fakeTicker struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	period time.Duration
+	next   time.Time // момент следующего тика
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+// Stop снимает тикер с часов; повторный вызов ничего не делает.
+func (t *fakeTicker) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.tickers {
+		if other == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			return
+		}
+	}
+}
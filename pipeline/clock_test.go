@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+)
+
+// useFakeClock подменяет часы пакета на FakeClock до конца теста.
+func useFakeClock(t *testing.T) *FakeClock {
+	t.Helper()
+	c := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(c)
+	t.Cleanup(func() { SetClock(nil) })
+	return c
+}
+
+// waitWaiters ждёт, пока на часах c не окажется n ожиданий.
+func waitWaiters(t *testing.T, c *FakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.Waiters() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ожиданий на часах %d, ожидалось %d", c.Waiters(), n)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+func TestFakeClockAfterFiresAtDeadline(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	ch := c.After(10 * time.Millisecond)
+	c.Advance(9 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("After сработал раньше срока")
+	default:
+	}
+	c.Advance(time.Millisecond)
+	select {
+	case at := <-ch:
+		if !at.Equal(time.Unix(0, 0).Add(10 * time.Millisecond)) {
+			t.Fatalf("After сработал в %v", at)
+		}
+	default:
+		t.Fatal("After не сработал в срок")
+	}
+	if c.Waiters() != 0 {
+		t.Fatalf("осталось ожиданий: %d", c.Waiters())
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	ticker := c.NewTicker(10 * time.Millisecond)
+	tick := func() (time.Time, bool) {
+		select {
+		case at := <-ticker.C():
+			return at, true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	c.Advance(9 * time.Millisecond)
+	if _, ok := tick(); ok {
+		t.Fatal("тикер сработал раньше периода")
+	}
+	c.Advance(time.Millisecond)
+	if at, ok := tick(); !ok || !at.Equal(time.Unix(0, 0).Add(10*time.Millisecond)) {
+		t.Fatalf("первый тик: (%v, %v)", at, ok)
+	}
+
+	// несколько периодов сразу дают один тик, а следующий приходит по
+	// сетке от начального момента
+	c.Advance(35 * time.Millisecond)
+	if _, ok := tick(); !ok {
+		t.Fatal("тикер не сработал после нескольких периодов")
+	}
+	if _, ok := tick(); ok {
+		t.Fatal("пропущенные тики накопились")
+	}
+	c.Advance(5 * time.Millisecond)
+	if _, ok := tick(); !ok {
+		t.Fatal("тикер не сработал на отметке 50ms")
+	}
+
+	if c.Waiters() != 1 {
+		t.Fatalf("ожиданий на часах %d, ожидался работающий тикер", c.Waiters())
+	}
+	ticker.Stop()
+	ticker.Stop()
+	c.Advance(time.Second)
+	if _, ok := tick(); ok || c.Waiters() != 0 {
+		t.Fatalf("остановленный тикер сработал или остался на часах (%d)", c.Waiters())
+	}
+}
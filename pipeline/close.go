@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDoubleClose сообщает о попытке закрыть уже закрытый канал.
+var ErrDoubleClose = errors.New("повторное закрытие канала")
+
+// Каналы конвейера закрывает только их владелец: входной канал — генератор,
+// выходной канал воркера — сам воркер, результирующий канал — сборщик.
+// Стадии закрывают свои каналы через closeOwned. В Run каждому каналу
+// соответствует свой sync.Once (см. closeGuard), поэтому второе закрытие
+// того же канала — например, когда два генератора по ошибке пишут в один
+// канал — не паникует, а возвращает ErrDoubleClose, и Run сообщает её
+// вызывающему.
+
+// closeGuard — владельцы каналов одного запуска Run: sync.Once на каждый
+// закрываемый канал и накопленные ошибки закрытия.
+type closeGuard struct {
+	mu    sync.Mutex
+	onces map[any]*sync.Once
+	errs  []error
+}
+
+// closeGuardKey — ключ closeGuard в контексте.
+type closeGuardKey struct{}
+
+// withCloseGuard возвращает копию ctx с новым closeGuard; его видят все
+// стадии, получившие производный контекст.
+func withCloseGuard(ctx context.Context) (context.Context, *closeGuard) {
+	g := &closeGuard{onces: make(map[any]*sync.Once)}
+	return context.WithValue(ctx, closeGuardKey{}, g), g
+}
+
+// once возвращает sync.Once канала ch, создавая его при первом обращении.
+func (g *closeGuard) once(ch any) *sync.Once {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	o, ok := g.onces[ch]
+	if !ok {
+		o = &sync.Once{}
+		g.onces[ch] = o
+	}
+	return o
+}
+
+// record запоминает ошибку закрытия канала стадии stage.
+func (g *closeGuard) record(stage string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs = append(g.errs, fmt.Errorf("стадия %s: %w", stage, err))
+}
+
+// Err возвращает все ошибки закрытия, объединённые errors.Join, или nil.
+func (g *closeGuard) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
+
+// closeChan закрывает ch и возвращает ErrDoubleClose вместо паники, если ch
+// уже закрыт.
+func closeChan[T any](ch chan<- T) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = ErrDoubleClose
+		}
+	}()
+	close(ch)
+	return nil
+}
+
+// closeOwned закрывает канал ch, которым владеет стадия stage (см.
+// StageGenerator и соседние константы), и возвращает ErrDoubleClose, если
+// канал уже закрыт. Внутри Run закрытие проходит через sync.Once канала, а
+// ошибка дополнительно попадает в ошибку Run; вне Run повторное закрытие
+// распознаётся по панике close. В обоих случаях ошибка журналируется.
+func closeOwned[T any](ctx context.Context, ch chan<- T, stage string) error {
+	var err error
+	if g, ok := ctx.Value(closeGuardKey{}).(*closeGuard); ok {
+		err = ErrDoubleClose
+		g.once(ch).Do(func() { err = closeChan(ch) })
+		if err != nil {
+			g.record(stage, err)
+		}
+	} else {
+		err = closeChan(ch)
+	}
+
+	if err != nil {
+		logFor(ctx).ErrorContext(ctx, "канал стадии закрыт не её владельцем", "stage", stage, "error", err)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCloseOwnedDoubleClose(t *testing.T) {
+	ctx, guard := withCloseGuard(context.Background())
+	ch := make(chan int64)
+
+	if err := closeOwned(ctx, ch, StageWorker); err != nil {
+		t.Fatalf("первое закрытие: %v", err)
+	}
+	if err := closeOwned(ctx, ch, StageWorker); !errors.Is(err, ErrDoubleClose) {
+		t.Fatalf("второе закрытие вернуло %v, ожидалась ErrDoubleClose", err)
+	}
+	if err := guard.Err(); !errors.Is(err, ErrDoubleClose) {
+		t.Fatalf("ошибка не сохранена для Run: %v", err)
+	}
+
+	// без Run повторное закрытие тоже не паникует
+	if err := closeOwned(context.Background(), ch, StageWorker); !errors.Is(err, ErrDoubleClose) {
+		t.Fatalf("закрытие вне Run вернуло %v, ожидалась ErrDoubleClose", err)
+	}
+}
+
+func TestRunReportsDoubleCloseOfInput(t *testing.T) {
+	// генератор закрывает входной канал сам, а затем ещё раз как его
+	// владелец: Run не должен паниковать и должен вернуть ErrDoubleClose
+	gen := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorN(ctx, ch, 5, fn)
+		closeOwned(ctx, ch, StageGenerator)
+	}
+	result, err := RunWith(context.Background(), 2, gen, WithDelay(0))
+	if !errors.Is(err, ErrDoubleClose) {
+		t.Fatalf("Run вернул %v, ожидалась ErrDoubleClose", err)
+	}
+	if result.StopReason != StopError {
+		t.Errorf("StopReason = %v, ожидалась %v", result.StopReason, StopError)
+	}
+	if result.InputCount != 5 {
+		t.Errorf("отправлено %d чисел, ожидалось 5", result.InputCount)
+	}
+	if err := result.Verify(); err != nil {
+		t.Error(err)
+	}
+}
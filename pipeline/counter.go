@@ -0,0 +1,62 @@
+package pipeline
+
+import "sync/atomic"
+
+// DefaultCoalesce — через сколько чисел CoalescingCounter по умолчанию
+// публикует накопленные итоги.
+const DefaultCoalesce = 1024
+
+// CoalescingCounter считает количество и сумму чисел для функции fn
+// генератора, реже обращаясь к общим атомарным счётчикам: Add копит итоги в
+// обычных полях и публикует их атомарно раз в every чисел, а остаток — при
+// вызове Flush. Методы Add и Flush должен вызывать один владелец (обычно
+// горутина генератора), а Load можно вызывать из любой горутины.
+//
+// Плата за это — отставание снимка: до вызова Flush Load может не учитывать
+// до every-1 последних чисел. Поэтому CoalescingCounter не подходит
+// там, где важна свежесть счётчика на медленном потоке, например для
+// WithIdleTimeout.
+type CoalescingCounter struct {
+	every int64
+
+	// pending и pendingSum принадлежат владельцу
+	pending    int64
+	pendingSum int64
+
+	count int64
+	sum   int64
+}
+
+// NewCoalescingCounter создаёт счётчик, публикующий итоги раз в every
+// чисел; при every < 1 используется DefaultCoalesce.
+func NewCoalescingCounter(every int) *CoalescingCounter {
+	if every < 1 {
+		every = DefaultCoalesce
+	}
+	return &CoalescingCounter{every: int64(every)}
+}
+
+// Add учитывает число v. Подходит в качестве fn генератора.
+func (c *CoalescingCounter) Add(v int64) {
+	c.pending++
+	c.pendingSum += v
+	if c.pending >= c.every {
+		c.Flush()
+	}
+}
+
+// Flush публикует накопленные итоги. Владелец вызывает Flush по окончании
+// генерации, чтобы Load вернул точные значения.
+func (c *CoalescingCounter) Flush() {
+	if c.pending == 0 {
+		return
+	}
+	atomic.AddInt64(&c.count, c.pending)
+	atomic.AddInt64(&c.sum, c.pendingSum)
+	c.pending, c.pendingSum = 0, 0
+}
+
+// Load возвращает опубликованные количество и сумму.
+func (c *CoalescingCounter) Load() (count, sum int64) {
+	return atomic.LoadInt64(&c.count), atomic.LoadInt64(&c.sum)
+}
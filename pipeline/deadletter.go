@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeadLetter собирает числа, которые воркеры не смогли доставить: отклонённые
+// преобразованием или не отправленные в выходной канал из-за отмены
+// контекста. Собственная горутина DeadLetter читает канал недоставленных
+// чисел до вызова Close, в том числе после отмены контекста конвейера,
+// поэтому отправители никогда не остаются заблокированными на нём.
+type DeadLetter struct {
+	ch     chan letter
+	done   chan struct{}
+	handle func(v int64, err error)
+	once   sync.Once
+	count  int64
+	sum    int64
+}
+
+// letter — недоставленное число и причина, по которой оно не доставлено.
+type letter struct {
+	v   int64
+	err error
+}
+
+// NewDeadLetter создаёт DeadLetter и запускает горутину, которая читает
+// недоставленные числа. Если handle не nil, она вызывается для каждого числа
+// из этой горутины, то есть последовательно.
+func NewDeadLetter(handle func(v int64, err error)) *DeadLetter {
+	d := &DeadLetter{
+		ch:     make(chan letter),
+		done:   make(chan struct{}),
+		handle: handle,
+	}
+	go d.drain()
+	return d
+}
+
+// drain читает недоставленные числа до закрытия канала.
+func (d *DeadLetter) drain() {
+	defer close(d.done)
+
+	for l := range d.ch {
+		atomic.AddInt64(&d.count, 1)
+		atomic.AddInt64(&d.sum, l.v)
+		if d.handle != nil {
+			d.handle(l.v, l.err)
+		}
+	}
+}
+
+// Send передаёт недоставленное число v с причиной err. Send нельзя вызывать
+// после Close.
+func (d *DeadLetter) Send(v int64, err error) {
+	d.ch <- letter{v: v, err: err}
+}
+
+// Close дожидается обработки всех переданных чисел и останавливает горутину
+// DeadLetter. Close вызывают после завершения всех отправителей; повторный
+// вызов безопасен.
+func (d *DeadLetter) Close() {
+	d.once.Do(func() { close(d.ch) })
+	<-d.done
+}
+
+// Count возвращает количество недоставленных чисел.
+func (d *DeadLetter) Count() int64 {
+	return atomic.LoadInt64(&d.count)
+}
+
+// Sum возвращает сумму недоставленных чисел.
+func (d *DeadLetter) Sum() int64 {
+	return atomic.LoadInt64(&d.sum)
+}
+
+// WorkerDeadLetter читает числа из канала in и применяет к каждому
+// transform: при успехе результат отправляется в out, а при ошибке (в том
+// числе *PanicError, если transform запаниковал) исходное число вместе с
+// ошибкой передаётся в dl. Если результат не удалось
+// отправить в out из-за отмены ctx, исходное число тоже передаётся в dl с
+// ошибкой ctx.Err(). Когда закрывается in или отменяется ctx,
+// WorkerDeadLetter закрывает out и завершает работу; dl закрывает его
+// владелец.
+func WorkerDeadLetter(ctx context.Context, in <-chan int64, out chan<- int64, dl *DeadLetter, transform func(int64) (int64, error)) {
+	transformForward(ctx, in, out, 0, dl, transform, nil)
+}
+
+// transformForward — WorkerDeadLetter с паузой delay после каждого
+// доставленного числа. Если hist не nil, в неё передаётся время обработки
+// каждого доставленного числа, как у forward.
+func transformForward(ctx context.Context, in <-chan int64, out chan<- int64, delay time.Duration, dl *DeadLetter, transform func(int64) (int64, error), hist func(time.Duration)) {
+	defer closeOwned(ctx, out, StageWorker)
+
+	for {
+		v, ok := recvCtx(ctx, in)
+		if !ok {
+			return
+		}
+
+		var start time.Time
+		if hist != nil {
+			start = clk().Now()
+		}
+
+		res, err := safeTransform(ctx, transform, v)
+		if err != nil {
+			dl.Send(v, err)
+			continue
+		}
+
+		if !sendCtx(ctx, out, res) {
+			dl.Send(v, ctx.Err())
+			return
+		}
+		if delay > 0 {
+			clk().Sleep(delay)
+		}
+		if hist != nil {
+			hist(clk().Now().Sub(start))
+		}
+	}
+}
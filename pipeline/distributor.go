@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Distributor выбирает, какому воркеру отправить очередное число, когда у
+// воркеров отдельные входные каналы (см. FanOutWith). Next возвращает индекс
+// воркера от 0 до количества воркеров; диспетчер вызывает его из одной
+// горутины.
+type Distributor interface {
+	Next() int
+}
+
+// RoundRobin отправляет числа воркерам по очереди: 0, 1, …, n-1, 0, ….
+type RoundRobin struct {
+	n    int
+	next int
+}
+
+// NewRoundRobin создаёт RoundRobin для n воркеров.
+func NewRoundRobin(n int) *RoundRobin {
+	return &RoundRobin{n: n}
+}
+
+// Next возвращает индекс следующего по очереди воркера.
+func (r *RoundRobin) Next() int {
+	i := r.next
+	r.next = (r.next + 1) % r.n
+	return i
+}
+
+// Random отправляет каждое число случайному воркеру с равной вероятностью.
+type Random struct {
+	n int
+}
+
+// NewRandom создаёт Random для n воркеров.
+func NewRandom(n int) *Random {
+	return &Random{n: n}
+}
+
+// Next возвращает индекс случайного воркера.
+func (r *Random) Next() int {
+	return rand.IntN(r.n)
+}
+
+// Weighted распределяет числа пропорционально весам воркеров по плавному
+// взвешенному циклическому расписанию (smooth weighted round-robin): воркер
+// с весом 3 получает три числа из каждых sum(weights), причём его очередь
+// перемежается с очередями остальных.
+type Weighted struct {
+	weights []int
+	current []int
+	total   int
+}
+
+// NewWeighted создаёт Weighted для len(weights) воркеров. Неположительный
+// вес считается равным 1.
+func NewWeighted(weights []int) *Weighted {
+	w := &Weighted{
+		weights: make([]int, len(weights)),
+		current: make([]int, len(weights)),
+	}
+	for i, v := range weights {
+		w.weights[i] = max(v, 1)
+		w.total += w.weights[i]
+	}
+	return w
+}
+
+// Next возвращает индекс воркера, чья очередь подошла.
+func (w *Weighted) Next() int {
+	best := 0
+	for i := range w.weights {
+		w.current[i] += w.weights[i]
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= w.total
+	return best
+}
+
+// LeastLoaded отправляет число воркеру, который к этому моменту обработал
+// меньше всего чисел, по счётчикам amounts (см. WithDistributor). При
+// равенстве выбирается воркер с меньшим индексом.
+type LeastLoaded struct {
+	amounts []int64
+}
+
+// NewLeastLoaded создаёт LeastLoaded, читающий счётчики amounts; их длина
+// задаёт количество воркеров. Счётчики читаются атомарно.
+func NewLeastLoaded(amounts []int64) Distributor {
+	return &LeastLoaded{amounts: amounts}
+}
+
+// Next возвращает индекс наименее загруженного воркера.
+func (l *LeastLoaded) Next() int {
+	best, least := 0, atomic.LoadInt64(&l.amounts[0])
+	for i := 1; i < len(l.amounts); i++ {
+		if n := atomic.LoadInt64(&l.amounts[i]); n < least {
+			best, least = i, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,7 @@
+// Package pipeline содержит строительные блоки конвейера обработки чисел:
+// генератор последовательности, обрабатывающие горутины (воркеры) и сборщик,
+// объединяющий их выходные каналы в один.
+//
+// Пакет не завершает процесс и не читает флаги командной строки, поэтому
+// его можно встраивать в собственные программы.
+package pipeline
@@ -0,0 +1,44 @@
+package pipeline
+
+import "sync/atomic"
+
+// ErrorCollector — общий канал ошибок для нескольких воркеров, например
+// WorkerFunc, который закрывается ровно один раз, когда завершились все
+// воркеры. Каждый воркер после своего завершения вызывает Done; канал
+// закрывает последний вызов, поэтому ни один воркер не может отправить
+// ошибку в уже закрытый канал.
+type ErrorCollector struct {
+	errs chan error
+	left atomic.Int64
+}
+
+// NewErrorCollector создаёт ErrorCollector для numWorkers воркеров. При
+// numWorkers < 1 канал закрывается сразу.
+func NewErrorCollector(numWorkers int) *ErrorCollector {
+	c := &ErrorCollector{errs: make(chan error)}
+	c.left.Store(int64(numWorkers))
+	if numWorkers < 1 {
+		close(c.errs)
+	}
+	return c
+}
+
+// Errs возвращает канал, в который воркеры отправляют ошибки.
+func (c *ErrorCollector) Errs() chan<- error {
+	return c.errs
+}
+
+// Errors возвращает канал для чтения ошибок; он закрывается после того, как
+// Done вызван для всех воркеров. Читать его нужно до закрытия, иначе
+// воркеры заблокируются на отправке.
+func (c *ErrorCollector) Errors() <-chan error {
+	return c.errs
+}
+
+// Done отмечает, что очередной воркер завершился и больше не отправит
+// ошибок. Вызовы сверх numWorkers ничего не делают.
+func (c *ErrorCollector) Done() {
+	if c.left.Add(-1) == 0 {
+		close(c.errs)
+	}
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestErrorCollectorWithConcurrentWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const workers, n = 4, 400
+	in := make(chan int64)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	collector := NewErrorCollector(workers)
+	outs := make([]<-chan int64, workers)
+	for i := range outs {
+		out := make(chan int64)
+		outs[i] = out
+		go func() {
+			defer collector.Done()
+			WorkerFunc(ctx, i, in, out, collector.Errs(), rejectOdd)
+		}()
+	}
+	passed := make(chan int64)
+	go func() {
+		count, _ := Aggregate(FanIn(outs...))
+		passed <- count
+	}()
+
+	// Errors закрывается только после Done всех воркеров, поэтому цикл
+	// завершится, а лишнего закрытия не будет
+	var failed int64
+	for err := range collector.Errors() {
+		if !errors.Is(err, errOdd) {
+			t.Errorf("неожиданная ошибка: %v", err)
+		}
+		failed++
+	}
+	if got := <-passed; got != n/2 || failed != n/2 {
+		t.Fatalf("пропущено %d, ошибок %d, ожидалось по %d", got, failed, n/2)
+	}
+
+	// вызовы сверх количества воркеров ничего не делают
+	collector.Done()
+}
+
+func TestErrorCollectorNoWorkers(t *testing.T) {
+	if _, ok := <-NewErrorCollector(0).Errors(); ok {
+		t.Fatal("канал ошибок без воркеров не закрыт")
+	}
+}
@@ -0,0 +1,55 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+func ExamplePipeline() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// останавливаем генерацию после сотого числа
+	var generated int
+	result, err := pipeline.New(ctx).
+		WithWorkers(4).
+		WithDelay(0).
+		Generate(func(int64) {
+			if generated++; generated == 100 {
+				cancel()
+			}
+		}).
+		Collect()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("воркеров:", len(result.PerChannel))
+	fmt.Println("не меньше 100 чисел:", result.InputCount >= 100)
+	fmt.Println("ошибки сверки:", result.Verify())
+	// Output:
+	// воркеров: 4
+	// не меньше 100 чисел: true
+	// ошибки сверки: <nil>
+}
+
+func ExampleCollect() {
+	in := make(chan int64)
+	go func() {
+		defer close(in)
+		for _, v := range []int64{3, 1, 3, 2, 1} {
+			in <- v
+		}
+	}()
+
+	// количество различных чисел не зависит от порядка, в котором они
+	// приходят на выход конвейера
+	distinct := pipeline.Collect(in, map[int64]bool{}, func(seen map[int64]bool, v int64) map[int64]bool {
+		seen[v] = true
+		return seen
+	})
+	fmt.Println(len(distinct))
+	// Output: 3
+}
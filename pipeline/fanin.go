@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// FanIn объединяет каналы channels в один и возвращает его. Для каждого
+// входного канала запускается горутина, пересылающая из него числа;
+// результирующий канал закрывается, когда все входные каналы закрыты и
+// прочитаны. Порядок чисел из разных каналов не сохраняется.
+func FanIn(channels ...<-chan int64) <-chan int64 {
+	return collect(context.Background(), nil, channels, 0, nil)
+}
+
+// collect — сборщик конвейера: объединяет channels в канал с буфером size и,
+// если amounts не nil, подсчитывает в amounts[i] количество чисел из
+// channels[i], действительно пересланных в результирующий канал. Если
+// processed не nil, она вызывается для каждого числа после его пересылки в
+// результирующий канал; паника в ней журналируется и не останавливает
+// сборщик.
+//
+// Сборщик пересылает всё, что приходит, пока ctx не отменён. Чтобы числа,
+// уже принятые воркерами, не терялись при остановке генерации, вызывающий
+// передаёт ctx, который не отменяется вместе с генерацией (режим дренажа);
+// отмена ctx означает аварийное прекращение работы: горутины сборщика
+// выходят, не дочитав свои каналы, даже если результирующий канал никто не
+// читает, а число, которое не удалось переслать, в amounts не попадает.
+func collect(ctx context.Context, amounts []int64, channels []<-chan int64, size int, processed func(worker int, v int64)) <-chan int64 {
+	// chOut — канал, в который будут отправляться числа из channels[i]
+	chOut := make(chan int64, size)
+
+	var wg sync.WaitGroup
+	for i, in := range channels {
+		wg.Add(1)
+		go func(in <-chan int64, i int) {
+			defer wg.Done()
+			for {
+				v, ok := recvCtx(ctx, in)
+				if !ok {
+					return
+				}
+
+				if !sendCtx(ctx, chOut, v) {
+					return
+				}
+
+				// число учитывается только после пересылки, чтобы
+				// amounts совпадали с тем, что дошло до выхода. Каждая
+				// горутина пишет только в свой элемент amounts, но читать
+				// счётчики могут и другие горутины (например, отчёт о ходе
+				// работы), поэтому обновление атомарное
+				if amounts != nil {
+					atomic.AddInt64(&amounts[i], 1)
+				}
+				if processed != nil {
+					safeProcessed(ctx, processed, i, v)
+				}
+			}
+		}(in, i)
+	}
+
+	go func() {
+		// ждём завершения работы всех горутин для channels
+		wg.Wait()
+		// закрываем результирующий канал
+		closeOwned(ctx, chOut, StageFanIn)
+	}()
+
+	return chOut
+}
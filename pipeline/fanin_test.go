@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollectCancelWithStalledReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// воркеры бесконечно шлют числа, а получатель читает только 10 и
+	// перестаёт
+	const workers = 3
+	channels := make([]<-chan int64, workers)
+	for i := range channels {
+		ch := make(chan int64)
+		channels[i] = ch
+		go func() {
+			for v := int64(1); ; v++ {
+				select {
+				case ch <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	amounts := make([]int64, workers)
+	out := collect(ctx, amounts, channels, 0, nil)
+
+	var received int64
+	for ; received < 10; received++ {
+		<-out
+	}
+	time.Sleep(10 * time.Millisecond) // горутины сборщика стоят на отправке
+	cancel()
+
+	// отмена должна разблокировать сборщик: out закрывается, а лишние
+	// числа, которые не удалось переслать, в amounts не попадают
+	rest := make(chan int64)
+	go func() {
+		var n int64
+		for range out {
+			n++
+		}
+		rest <- n
+	}()
+	select {
+	case n := <-rest:
+		received += n
+	case <-time.After(time.Second):
+		t.Fatal("сборщик не завершился после отмены")
+	}
+
+	var counted int64
+	for i := range amounts {
+		counted += atomic.LoadInt64(&amounts[i])
+	}
+	if counted != received {
+		t.Fatalf("в amounts учтено %d чисел, а получено %d", counted, received)
+	}
+}
+
+// После ранней отмены горутины сборщика не должны оставаться висеть, даже
+// если получатель бросил out, не дочитав его.
+func TestCollectNoLeakWithAbandonedReader(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	const workers = 4
+	channels := make([]<-chan int64, workers)
+	for i := range channels {
+		ch := make(chan int64)
+		channels[i] = ch
+		go func() {
+			defer close(ch)
+			for v := int64(1); ; v++ {
+				select {
+				case ch <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	out := collect(ctx, make([]int64, workers), channels, 0, nil)
+	<-out
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("горутин %d, до запуска было %d:\n%s",
+				runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFanInMergesAllValues(t *testing.T) {
+	inputs := [][]int64{{1, 2, 3}, {10, 20}, {100, 200, 300, 1}}
+	channels := make([]<-chan int64, len(inputs))
+	want := map[int64]int{}
+	for i, values := range inputs {
+		ch := make(chan int64)
+		channels[i] = ch
+		go func() {
+			defer close(ch)
+			for _, v := range values {
+				ch <- v
+			}
+		}()
+		for _, v := range values {
+			want[v]++
+		}
+	}
+
+	got := map[int64]int{}
+	for v := range FanIn(channels...) {
+		got[v]++
+	}
+	if len(got) != len(want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+	for v, n := range want {
+		if got[v] != n {
+			t.Fatalf("число %d получено %d раз, ожидалось %d: %v", v, got[v], n, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package pipeline
+
+import "context"
+
+// FanOut создаёт numOut выходных каналов, запускает для каждого из них
+// Worker, читающий из общего канала in, и возвращает эти каналы.
+//
+// Числа распределяются без явной очереди: все воркеры соревнуются за чтение
+// из in, так что каждое число достаётся тому, кто освободился первым.
+// Порядок чисел между выходными каналами не сохраняется. Каждый выходной
+// канал закрывается, когда закрывается in или отменяется ctx.
+func FanOut(ctx context.Context, in <-chan int64, numOut int) []<-chan int64 {
+	return fanOut(ctx, in, numOut, func(ctx context.Context, _ int, in <-chan int64, out chan<- int64) {
+		Worker(ctx, in, out)
+	})
+}
+
+// workFunc — тело воркера с индексом index, читающего из in и пишущего в
+// out; оно обязано закрыть out при завершении.
+type workFunc func(ctx context.Context, index int, in <-chan int64, out chan<- int64)
+
+// fanOut создаёт numOut выходных каналов и для каждого запускает горутину
+// work, читающую из общего канала in.
+func fanOut(ctx context.Context, in <-chan int64, numOut int, work workFunc) []<-chan int64 {
+	// outs — слайс каналов, куда будут записываться числа из in
+	outs := make([]<-chan int64, numOut)
+	for i := 0; i < numOut; i++ {
+		// создаём каналы и для каждого из них запускаем воркер
+		out := make(chan int64)
+		outs[i] = out
+		go work(ctx, i, in, out)
+	}
+	return outs
+}
+
+// WeightedFanOut распределяет числа из in между len(weights) воркерами
+// пропорционально их весам по расписанию NewWeighted и возвращает выходные
+// каналы воркеров.
+//
+// В отличие от FanOut, у каждого воркера свой входной канал, который
+// наполняет отдельная горутина-диспетчер. Диспетчер ждёт, пока выбранный
+// воркер примет число, поэтому медленный воркер притормаживает остальных.
+func WeightedFanOut(ctx context.Context, in <-chan int64, weights []int) []<-chan int64 {
+	return FanOutWith(ctx, in, len(weights), NewWeighted(weights))
+}
+
+// FanOutWith запускает numOut воркеров, у каждого из которых свой входной
+// канал, и горутину-диспетчер, отправляющую каждое число из in воркеру с
+// индексом d.Next(). Возвращает выходные каналы воркеров. Когда закрывается
+// in или отменяется ctx, диспетчер закрывает входные каналы воркеров.
+//
+// Диспетчер ждёт, пока выбранный воркер примет число, поэтому медленный
+// воркер притормаживает остальных.
+func FanOutWith(ctx context.Context, in <-chan int64, numOut int, d Distributor) []<-chan int64 {
+	return dispatch(ctx, in, numOut, d.Next, func(ctx context.Context, _ int, in <-chan int64, out chan<- int64) {
+		Worker(ctx, in, out)
+	})
+}
+
+// dispatch запускает numOut воркеров work, у каждого из которых свой
+// входной канал, и горутину-диспетчер, отправляющую каждое число из in
+// воркеру с индексом next(). Когда закрывается in или отменяется ctx,
+// диспетчер закрывает входные каналы воркеров. Функция next вызывается
+// только из горутины-диспетчера.
+func dispatch(ctx context.Context, in <-chan int64, numOut int, next func() int, work workFunc) []<-chan int64 {
+	inputs := make([]chan int64, numOut)
+	outs := make([]<-chan int64, numOut)
+	for i := range inputs {
+		inputs[i] = make(chan int64)
+		out := make(chan int64)
+		outs[i] = out
+		go work(ctx, i, inputs[i], out)
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range inputs {
+				close(ch)
+			}
+		}()
+
+		for {
+			v, ok := recvCtx(ctx, in)
+			if !ok {
+				return
+			}
+			if !sendCtx(ctx, inputs[next()], v) {
+				return
+			}
+		}
+	}()
+
+	return outs
+}
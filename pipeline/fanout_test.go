@@ -0,0 +1,29 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWeightedFanOutFollowsWeights(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 600
+	weights := []int{1, 2, 3}
+	in := make(chan int64)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	amounts := make([]int64, len(weights))
+	count, _ := Aggregate(collect(ctx, amounts, WeightedFanOut(ctx, in, weights), 0, nil))
+	if count != n {
+		t.Fatalf("получено %d чисел, ожидалось %d", count, n)
+	}
+
+	for i, w := range weights {
+		want := int64(n * w / 6)
+		if diff := amounts[i] - want; diff < -want/20 || diff > want/20 {
+			t.Fatalf("воркер %d с весом %d получил %d чисел, ожидалось %d±5%%: %v",
+				i, w, amounts[i], want, amounts)
+		}
+	}
+}
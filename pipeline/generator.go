@@ -0,0 +1,302 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverflow сообщает, что следующее число последовательности не
+// помещается в int64.
+var ErrOverflow = errors.New("переполнение int64")
+
+// Generator генерирует последовательность чисел 1,2,3 и т.д. и
+// отправляет их в канал ch. При этом после записи в канал для каждого числа
+// вызывается функция fn. Она служит для подсчёта количества и суммы
+// сгенерированных чисел.
+//
+// Generator завершает работу и закрывает канал ch, когда отменяется ctx;
+// после отмены в канал не отправляется ни одного числа, а fn вызывается
+// ровно один раз для каждого реально отправленного числа. После числа
+// math.MaxInt64 Generator останавливается, так как следующее число не
+// помещается в int64 (см. GeneratorFrom).
+func Generator(ctx context.Context, ch chan<- int64, fn func(int64)) {
+	GeneratorFrom(ctx, ch, 1, fn, nil)
+}
+
+// GeneratorFrom генерирует последовательность seed, seed+1, seed+2 и т.д. по
+// тем же правилам, что и Generator. Вместо того чтобы после math.MaxInt64
+// перейти к отрицательным числам и испортить проверку сумм, GeneratorFrom
+// закрывает канал ch и, если onErr не nil, вызывает onErr(ErrOverflow).
+func GeneratorFrom(ctx context.Context, ch chan<- int64, seed int64, fn func(int64), onErr func(error)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for i := seed; ; i++ {
+		if !sendCtx(ctx, ch, i) {
+			return
+		}
+		fn(i)
+		if !canIncrement(i) {
+			if onErr != nil {
+				onErr(ErrOverflow)
+			}
+			return
+		}
+	}
+}
+
+// canIncrement сообщает, можно ли увеличить i на единицу без переполнения.
+func canIncrement(i int64) bool {
+	return i != math.MaxInt64
+}
+
+// GeneratorFloat — вариант Generator для чисел float64: генерирует
+// последовательность 1,2,3 и т.д. с теми же правилами отмены и вызова fn.
+func GeneratorFloat(ctx context.Context, ch chan<- float64, fn func(float64)) {
+	GeneratorOf(ctx, ch, 1, func(f float64) float64 { return f + 1 }, fn)
+}
+
+// GeneratorOf — обобщённый вариант Generator для произвольной
+// последовательности: первым в канал ch отправляется seed, каждое следующее
+// значение получается вызовом next от предыдущего. После каждой успешной
+// записи в канал вызывается fn.
+//
+// Как и Generator, GeneratorOf закрывает канал ch и завершает работу, когда
+// отменяется ctx. Состояние последовательности (например, для чисел
+// Фибоначчи) можно хранить в замыкании next.
+func GeneratorOf[T any](ctx context.Context, ch chan<- T, seed T, next func(T) T, fn func(T)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for v := seed; ; v = next(v) {
+		if !sendCtx(ctx, ch, v) {
+			return
+		}
+		fn(v)
+	}
+}
+
+// GeneratorFibonacci отправляет в канал ch числа Фибоначчи 1,1,2,3,5,8 и
+// т.д. по тем же правилам отмены и вызова fn, что и Generator. Последним
+// отправляется наибольшее число Фибоначчи, помещающееся в int64 (92-й
+// член); вместо переполнения GeneratorFibonacci закрывает канал ch.
+func GeneratorFibonacci(ctx context.Context, ch chan<- int64, fn func(int64)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for a, b := int64(1), int64(1); ; a, b = b, a+b {
+		if !sendCtx(ctx, ch, a) {
+			return
+		}
+		fn(a)
+		if b > math.MaxInt64-a {
+			// следующий после b член a+b не помещается в int64, но сам b
+			// ещё нужно отправить
+			if !sendCtx(ctx, ch, b) {
+				return
+			}
+			fn(b)
+			return
+		}
+	}
+}
+
+// GeneratorMerge запускает генераторы sources, каждый со своим внутренним
+// каналом, и пересылает их числа в общий канал ch, вызывая fn после каждой
+// успешной записи; функции fn самих источников ничего не делают. Порядок
+// чисел разных источников не определён. fn вызывается из нескольких
+// горутин одновременно.
+//
+// Канал ch закрывается, когда завершились все источники. После отмены ctx
+// числа в ch больше не отправляются, но внутренние каналы дочитываются до
+// закрытия, чтобы источники не остались заблокированными. Без источников
+// ch закрывается сразу.
+func GeneratorMerge(ctx context.Context, ch chan<- int64, fn func(int64), sources ...GeneratorFunc) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		src := make(chan int64)
+		go source(ctx, src, func(int64) {})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for v := range src {
+				if !sendCtx(ctx, ch, v) {
+					// источник сам остановится по ctx; дочитываем его
+					for range src {
+					}
+					return
+				}
+				fn(v)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// GeneratorRandom отправляет в канал ch псевдослучайные неотрицательные
+// числа rng.Int64() по тем же правилам отмены и вызова fn, что и Generator.
+// При одинаково засеянном rng последовательность повторяется, так что
+// запуски можно воспроизвести. rng используется только из горутины
+// GeneratorRandom.
+//
+// Суммы таких чисел быстро выходят за пределы int64. Run не переходит на
+// math/big: сложение int64 переполняется одинаково, в каком бы порядке ни
+// складывались числа, поэтому суммы на входе и выходе остаются сравнимыми,
+// а сам факт переполнения отмечается в Result.Overflow.
+func GeneratorRandom(ctx context.Context, ch chan<- int64, rng *rand.Rand, fn func(int64)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for {
+		v := rng.Int64()
+		if !sendCtx(ctx, ch, v) {
+			return
+		}
+		fn(v)
+	}
+}
+
+// GeneratorRandomN работает как GeneratorRandom, но останавливается сама,
+// отправив n чисел, как GeneratorN. С одинаково засеянным rng два запуска
+// отправляют одни и те же n чисел, так что их итоги совпадают.
+func GeneratorRandomN(ctx context.Context, ch chan<- int64, rng *rand.Rand, n int64, fn func(int64)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for i := int64(0); i < n; i++ {
+		v := rng.Int64()
+		if !sendCtx(ctx, ch, v) {
+			return
+		}
+		fn(v)
+	}
+}
+
+// GeneratorParallel генерирует числа 1,2,3 и т.д. в n горутинах,
+// отправляющих в общий канал ch. Горутина берёт следующее число из общего
+// счётчика только на время отправки и возвращает его, если отправка не
+// состоялась, поэтому отправленные числа — это всегда ровно 1..M без
+// пропусков и повторов, в том числе после отмены ctx. Отправки в ch идут по
+// одной, а fn вызывается после каждой успешной записи вне этой очереди, то
+// есть параллельно из нескольких горутин. Канал ch закрывается ровно один
+// раз, после завершения всех горутин, то есть после отмены ctx или после
+// отправки math.MaxInt64. При n <= 1 GeneratorParallel работает как
+// Generator.
+func GeneratorParallel(ctx context.Context, ch chan<- int64, n int, fn func(int64)) {
+	if n <= 1 {
+		Generator(ctx, ch, fn)
+		return
+	}
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	// next — следующее неотправленное число; mu удерживается от взятия
+	// числа до конца его отправки
+	var mu sync.Mutex
+	next := int64(1)
+	send := func() (int64, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		i := next
+		if i <= 0 || !sendCtx(ctx, ch, i) {
+			return 0, false
+		}
+		next++ // после math.MaxInt64 становится отрицательным
+		return i, true
+	}
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := send()
+				if !ok {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// GeneratorN генерирует числа 1,2,3 и т.д. так же, как Generator, но
+// останавливается сама, отправив ровно n чисел: после этого канал ch
+// закрывается, даже если ctx ещё не отменён. При n <= 0 канал закрывается
+// сразу.
+//
+// Отмена контекста имеет приоритет: если ctx отменяется раньше, чем
+// отправлены все n чисел, GeneratorN закрывает ch и завершается досрочно,
+// так что в канал может попасть меньше n чисел. Функция fn вызывается только
+// для реально отправленных чисел.
+func GeneratorN(ctx context.Context, ch chan<- int64, n int64, fn func(int64)) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	for i := int64(1); i <= n; i++ {
+		if !sendCtx(ctx, ch, i) {
+			return
+		}
+		fn(i)
+	}
+}
+
+// GeneratorRate генерирует числа 1,2,3 и т.д. так же, как Generator, но
+// отправляет не больше ratePerSec чисел в секунду: каждая отправка ждёт
+// тика общего тикера с периодом 1s/ratePerSec по часам пакета (см.
+// SetClock). Тики, пришедшие, пока отправка ждала получателя, отбрасываются,
+// поэтому медленный получатель не вызывает потом всплеска отправок.
+//
+// При ratePerSec <= 0 ограничение не действует и GeneratorRate работает как
+// Generator. Отмена ctx закрывает канал ch сразу, в том числе во время
+// ожидания тика.
+func GeneratorRate(ctx context.Context, ch chan<- int64, ratePerSec int, fn func(int64)) {
+	interval := time.Duration(0)
+	if ratePerSec > 0 {
+		interval = time.Second / time.Duration(ratePerSec)
+	}
+	if interval <= 0 {
+		Generator(ctx, ch, fn)
+		return
+	}
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	ticker := clk().NewTicker(interval)
+	defer ticker.Stop()
+	for i := int64(1); ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		if !sendCtx(ctx, ch, i) {
+			return
+		}
+		fn(i)
+	}
+}
+
+// Sample оборачивает функцию fn генератора так, что она вызывается только
+// для каждого every-го числа: при n вызовах обёртки fn срабатывает ровно
+// n/every раз (с отбрасыванием остатка). Выборка подходит для дорогих
+// действий вроде журналирования; точный подсчёт должен оставаться в
+// отдельной функции, которая вызывается для каждого числа. Обёртку можно
+// вызывать из нескольких горутин. При every <= 1 fn вызывается для каждого
+// числа.
+func Sample(fn func(int64), every int) func(int64) {
+	if every <= 1 {
+		return fn
+	}
+
+	var calls int64
+	return func(v int64) {
+		if atomic.AddInt64(&calls, 1)%int64(every) == 0 {
+			fn(v)
+		}
+	}
+}
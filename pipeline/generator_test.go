@@ -0,0 +1,326 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGeneratorSequence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan int64)
+	go Generator(ctx, ch, func(int64) {})
+	for want := int64(1); want <= 1000; want++ {
+		if got := <-ch; got != want {
+			t.Fatalf("получено %d, ожидалось %d", got, want)
+		}
+	}
+}
+
+func TestGeneratorCallsFnOncePerValue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls, sum atomic.Int64
+	ch := make(chan int64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Generator(ctx, ch, func(v int64) {
+			calls.Add(1)
+			sum.Add(v)
+		})
+	}()
+
+	var received, receivedSum int64
+	for v := range ch {
+		received++
+		receivedSum += v
+		if received == 500 {
+			cancel()
+		}
+	}
+	<-done
+
+	if calls.Load() != received || sum.Load() != receivedSum {
+		t.Fatalf("fn вызвана %d раз с суммой %d, получено %d чисел с суммой %d",
+			calls.Load(), sum.Load(), received, receivedSum)
+	}
+}
+
+func TestGeneratorSendsNothingAfterDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int64
+	ch := make(chan int64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Generator(ctx, ch, func(int64) { calls.Add(1) })
+	}()
+
+	for i := 0; i < 10; i++ {
+		<-ch
+	}
+	cancel()
+	<-done
+
+	for v := range ch {
+		t.Errorf("после отмены получено число %d", v)
+	}
+	if n := calls.Load(); n != 10 {
+		t.Errorf("fn вызвана %d раз, ожидалось 10", n)
+	}
+}
+
+func TestGeneratorRandomSameSeedSameSummary(t *testing.T) {
+	run := func() Result {
+		t.Helper()
+		rng := rand.New(rand.NewPCG(42, 0))
+		result, err := RunWith(context.Background(), 4, func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+			GeneratorRandomN(ctx, ch, rng, 1000, fn)
+		}, WithDelay(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := result.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	a, b := run(), run()
+	if a.InputCount != 1000 || a.StopReason != StopCount {
+		t.Fatalf("отправлено %d чисел (%v), ожидалось 1000 (count)", a.InputCount, a.StopReason)
+	}
+	// разбивка по каналам и время блокировок зависят от планировщика, а
+	// итоги по числам — только от зерна
+	if a.InputSum != b.InputSum || a.OutputSum != b.OutputSum || a.OutputCount != b.OutputCount ||
+		a.Min != b.Min || a.Max != b.Max || a.Overflow != b.Overflow {
+		t.Fatalf("итоги запусков с одним зерном различаются:\n%v\n%v", a, b)
+	}
+}
+
+func TestGeneratorOfDoubling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan int64)
+	go GeneratorOf(ctx, ch, 1, func(v int64) int64 { return v * 2 }, func(int64) {})
+	for i := 0; i < 62; i++ {
+		if got, want := <-ch, int64(1)<<i; got != want {
+			t.Fatalf("значение %d: получено %d, ожидалось %d", i, got, want)
+		}
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestGeneratorOfStrings(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var seen []string
+	ch := make(chan string)
+	go GeneratorOf(ctx, ch, "a", func(s string) string { return s + "a" }, func(s string) { seen = append(seen, s) })
+	for _, want := range []string{"a", "aa", "aaa", "aaaa"} {
+		if got := <-ch; got != want {
+			t.Fatalf("получено %q, ожидалось %q", got, want)
+		}
+	}
+	cancel()
+	for range ch {
+	}
+	// канал закрыт, значит генератор вернулся и seen больше не меняется
+	if len(seen) < 4 || seen[3] != "aaaa" {
+		t.Fatalf("fn вызвана для %q", seen)
+	}
+}
+
+// За секунду по часам пакета GeneratorRate должен отправить ровно
+// ratePerSec чисел, по одному на тик, а после отмены — снять тикер с часов.
+func TestGeneratorRateOneValuePerTick(t *testing.T) {
+	c := useFakeClock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const rate = 100
+	ch := make(chan int64)
+	go GeneratorRate(ctx, ch, rate, func(int64) {})
+	waitWaiters(t, c, 1) // тикер заведён
+
+	recv := func() (int64, bool) {
+		select {
+		case v := <-ch:
+			return v, true
+		case <-time.After(100 * time.Millisecond):
+			return 0, false
+		}
+	}
+	for want := int64(1); want <= rate; want++ {
+		c.Advance(time.Second/rate - time.Millisecond)
+		select {
+		case v := <-ch:
+			t.Fatalf("число %d отправлено раньше тика", v)
+		default:
+		}
+		c.Advance(time.Millisecond)
+		if v, ok := recv(); !ok || v != want {
+			t.Fatalf("на тике %d получено (%d, %v), ожидалось %d", want, v, ok, want)
+		}
+	}
+
+	// пока получатель не читает, тики отбрасываются, а не копятся
+	c.Advance(5 * time.Second / rate)
+	if v, ok := recv(); !ok || v != rate+1 {
+		t.Fatalf("после пропущенных тиков получено (%d, %v), ожидалось %d", v, ok, rate+1)
+	}
+	if v, ok := recv(); ok {
+		t.Fatalf("после пропущенных тиков отправлено лишнее число %d", v)
+	}
+
+	cancel()
+	for range ch {
+	}
+	waitWaiters(t, c, 0)
+}
+
+func TestGeneratorFromStopsAtMaxInt64(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var gotErr error
+	ch := make(chan int64)
+	go GeneratorFrom(ctx, ch, math.MaxInt64-2, func(int64) {}, func(err error) { gotErr = err })
+	var got []int64
+	for v := range ch {
+		got = append(got, v)
+	}
+	want := []int64{math.MaxInt64 - 2, math.MaxInt64 - 1, math.MaxInt64}
+	if !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+	if !errors.Is(gotErr, ErrOverflow) {
+		t.Fatalf("onErr получила %v, ожидалась ErrOverflow", gotErr)
+	}
+}
+
+func TestSampleFiresEveryNth(t *testing.T) {
+	var fired atomic.Int64
+	sampled := Sample(func(int64) { fired.Add(1) }, 7)
+
+	// обёртку вызывают несколько горутин, как у GeneratorParallel
+	const goroutines, perGoroutine = 4, 250
+	done := make(chan struct{})
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < perGoroutine; i++ {
+				sampled(int64(i))
+			}
+		}()
+	}
+	for g := 0; g < goroutines; g++ {
+		<-done
+	}
+	if got, want := fired.Load(), int64(goroutines*perGoroutine/7); got != want {
+		t.Fatalf("fn вызвана %d раз, ожидалось %d", got, want)
+	}
+
+	var every1 int
+	Sample(func(int64) { every1++ }, 1)(1)
+	if every1 != 1 {
+		t.Fatalf("при every 1 fn вызвана %d раз", every1)
+	}
+}
+
+func TestGeneratorFibonacci(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan int64)
+	go GeneratorFibonacci(ctx, ch, func(int64) {})
+	got := readAll(ch)
+	if want := []int64{1, 1, 2, 3, 5, 8, 13, 21, 34, 55}; !slices.Equal(got[:10], want) {
+		t.Fatalf("первые члены %v, ожидалось %v", got[:10], want)
+	}
+	// 92-й член — наибольший, помещающийся в int64
+	if len(got) != 92 {
+		t.Fatalf("отправлено %d членов, ожидалось 92", len(got))
+	}
+	if last := got[91]; last != 7540113804746346429 {
+		t.Fatalf("последний член %d, ожидалось 7540113804746346429", last)
+	}
+	for i := 2; i < len(got); i++ {
+		if got[i] != got[i-1]+got[i-2] {
+			t.Fatalf("член %d равен %d, а не сумме двух предыдущих", i+1, got[i])
+		}
+	}
+}
+
+func TestGeneratorMergeBoundedSources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorN(ctx, ch, 10, fn)
+	}
+	second := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		defer close(ch)
+		for v := int64(101); v <= 105; v++ {
+			if !sendCtx(ctx, ch, v) {
+				return
+			}
+			fn(v)
+		}
+	}
+
+	var calls atomic.Int64
+	ch := make(chan int64)
+	go GeneratorMerge(ctx, ch, func(int64) { calls.Add(1) }, first, second)
+	got := readAll(ch)
+	slices.Sort(got)
+	want := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 101, 102, 103, 104, 105}
+	if !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+	if calls.Load() != int64(len(want)) {
+		t.Fatalf("fn вызвана %d раз, ожидалось %d", calls.Load(), len(want))
+	}
+}
+
+func TestGeneratorParallelNoDuplicatesOrGaps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 3
+	var calls atomic.Int64
+	ch := make(chan int64)
+	go GeneratorParallel(ctx, ch, n, func(int64) { calls.Add(1) })
+	var got []int64
+	for len(got) < 3000 {
+		got = append(got, <-ch)
+	}
+	cancel()
+	got = append(got, readAll(ch)...)
+
+	// даже после отмены отправленные числа — это ровно 1..M
+	slices.Sort(got)
+	for i, v := range got {
+		if v != int64(i+1) {
+			t.Fatalf("на месте %d число %d, ожидалось %d", i, v, i+1)
+		}
+	}
+	if calls.Load() != int64(len(got)) {
+		t.Fatalf("fn вызвана %d раз, получено %d чисел", calls.Load(), len(got))
+	}
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets — границы корзин LatencyHistogram по умолчанию,
+// рассчитанные на паузу воркера порядка DefaultDelay.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// LatencyHistogram — гистограмма времени обработки чисел воркерами.
+// Record можно вызывать из нескольких воркеров одновременно: счётчики
+// корзин атомарные.
+type LatencyHistogram struct {
+	bounds []time.Duration
+	counts []int64 // len(bounds)+1; последняя корзина — больше всех границ
+	total  int64   // суммарная длительность в наносекундах
+	count  int64
+}
+
+// NewLatencyHistogram создаёт гистограмму с верхними границами корзин
+// bounds: длительность d попадает в первую корзину с d <= bounds[i], а
+// длительности больше всех границ — в дополнительную последнюю корзину.
+// Границы сортируются; при пустом bounds используется
+// DefaultLatencyBuckets.
+func NewLatencyHistogram(bounds []time.Duration) *LatencyHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	sorted := append([]time.Duration(nil), bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &LatencyHistogram{
+		bounds: sorted,
+		counts: make([]int64, len(sorted)+1),
+	}
+}
+
+// Record учитывает длительность d. Подходит как hist для WorkerTimed.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.total, int64(d))
+	atomic.AddInt64(&h.count, 1)
+}
+
+// Snapshot возвращает текущее содержимое гистограммы.
+func (h *LatencyHistogram) Snapshot() Latency {
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+	}
+	return Latency{
+		Bounds: append([]time.Duration(nil), h.bounds...),
+		Counts: counts,
+		Total:  time.Duration(atomic.LoadInt64(&h.total)),
+		Count:  atomic.LoadInt64(&h.count),
+	}
+}
+
+// Latency — снимок LatencyHistogram, по которому можно считать перцентили.
+type Latency struct {
+	Bounds []time.Duration `json:"bounds"` // верхние границы корзин
+	Counts []int64         `json:"counts"` // len(Bounds)+1 корзин; последняя — больше всех границ
+	Total  time.Duration   `json:"total"`  // суммарное время обработки
+	Count  int64           `json:"count"`  // количество измерений
+}
@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	// границы передаются не по порядку: гистограмма сортирует их сама
+	h := NewLatencyHistogram([]time.Duration{10 * time.Millisecond, time.Millisecond})
+	for _, d := range []time.Duration{
+		500 * time.Microsecond, time.Millisecond, // до 1ms включительно
+		2 * time.Millisecond, 10 * time.Millisecond, // до 10ms
+		time.Second, // больше всех границ
+	} {
+		h.Record(d)
+	}
+	s := h.Snapshot()
+	if want := []time.Duration{time.Millisecond, 10 * time.Millisecond}; !slices.Equal(s.Bounds, want) {
+		t.Fatalf("границы %v, ожидалось %v", s.Bounds, want)
+	}
+	if want := []int64{2, 2, 1}; !slices.Equal(s.Counts, want) {
+		t.Fatalf("корзины %v, ожидалось %v", s.Counts, want)
+	}
+	if s.Count != 5 || s.Total != 1013500*time.Microsecond {
+		t.Fatalf("измерений %d на %v, ожидалось 5 на 1.0135s", s.Count, s.Total)
+	}
+}
+
+func TestLatencyHistogramConcurrentRecord(t *testing.T) {
+	h := NewLatencyHistogram(nil)
+	const goroutines, perGoroutine = 8, 1000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				h.Record(time.Duration(i) * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := h.Snapshot()
+	var inBuckets int64
+	for _, c := range s.Counts {
+		inBuckets += c
+	}
+	if s.Count != goroutines*perGoroutine || inBuckets != s.Count {
+		t.Fatalf("измерений %d, в корзинах %d, ожидалось по %d", s.Count, inBuckets, goroutines*perGoroutine)
+	}
+	if want := time.Duration(goroutines*perGoroutine*(perGoroutine-1)/2) * time.Microsecond; s.Total != want {
+		t.Fatalf("суммарно %v, ожидалось %v", s.Total, want)
+	}
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// logger — журнал пакета; по умолчанию все записи отбрасываются.
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	SetLogger(nil)
+}
+
+// SetLogger задаёт журнал, в который конвейер пишет структурированные
+// события: итоги запуска и итоги по воркерам на уровне Info и расхождения
+// итогов на уровне Warn.
+// При l == nil события отбрасываются, это поведение по умолчанию.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	logger.Store(l)
+}
+
+// log возвращает текущий журнал пакета.
+func log() *slog.Logger {
+	return logger.Load()
+}
+
+// logFor возвращает журнал пакета с атрибутом run_id, если в ctx задан
+// идентификатор запуска (см. WithRunID).
+func logFor(ctx context.Context) *slog.Logger {
+	if id, ok := RunIDFrom(ctx); ok {
+		return log().With("run_id", id)
+	}
+	return log()
+}
+
+// logConfigured сообщает, задан ли журнал через SetLogger.
+func logConfigured() bool {
+	_, discard := log().Handler().(discardHandler)
+	return !discard
+}
+
+// logSummary пишет в logger итоги запуска по воркерам: по одной записи на
+// воркер с атрибутами worker, count и pct (доля в процентах) и итоговую
+// запись с числом воркеров, общим количеством и индексом равномерности.
+func logSummary(ctx context.Context, logger *slog.Logger, r Result) {
+	pct := r.PerChannelPercent()
+	for i, count := range r.PerChannel {
+		logger.InfoContext(ctx, "итоги воркера",
+			"worker", i,
+			"count", count,
+			"pct", pct[i],
+		)
+	}
+	logger.InfoContext(ctx, "итоги по воркерам",
+		"workers", len(r.PerChannel),
+		"count", r.OutputCount,
+		"fairness", r.Fairness(),
+	)
+}
+
+// discardHandler — обработчик slog, отбрасывающий все записи.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLogSummaryPerWorkerRecords(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	const numOut = 5
+	result, err := RunBounded(context.Background(), numOut, 100, WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var perWorker, summary int
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Msg     string  `json:"msg"`
+			Worker  int     `json:"worker"`
+			Count   int64   `json:"count"`
+			Pct     float64 `json:"pct"`
+			Workers int     `json:"workers"`
+		}
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("запись журнала не JSON: %v", err)
+		}
+		switch rec.Msg {
+		case "итоги воркера":
+			if rec.Worker != perWorker || rec.Count != result.PerChannel[rec.Worker] {
+				t.Fatalf("запись %d: воркер %d, count %d; разбивка %v", perWorker, rec.Worker, rec.Count, result.PerChannel)
+			}
+			perWorker++
+		case "итоги по воркерам":
+			if rec.Workers != numOut || rec.Count != result.OutputCount {
+				t.Fatalf("итоговая запись: воркеров %d, count %d", rec.Workers, rec.Count)
+			}
+			summary++
+		}
+	}
+	if perWorker != numOut || summary != 1 {
+		t.Fatalf("записей воркеров %d, итоговых %d; ожидалось %d и 1", perWorker, summary, numOut)
+	}
+}
@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// Observer получает уведомления о числах, проходящих через конвейер, —
+// например, чтобы обновлять метрики. Методы вызываются конкурентно из
+// разных горутин и должны быть быстрыми: они выполняются на пути каждого
+// числа.
+type Observer interface {
+	// Generated вызывается для каждого числа, отправленного генератором.
+	Generated(v int64)
+	// Processed вызывается для каждого числа, которое сборщик переслал из
+	// канала воркера с индексом worker в результирующий канал.
+	Processed(worker int, v int64)
+}
+
+// Option настраивает запуск конвейера в Run.
+type Option func(*config)
+
+// config — параметры запуска конвейера.
+type config struct {
+	observers   []Observer
+	delay       time.Duration
+	distributor func(amounts []int64) Distributor
+	transform   func(int64) (int64, error)
+	inputBuffer int
+	// outputBuffer — ёмкость результирующего канала; отрицательное значение
+	// означает ёмкость, равную количеству воркеров
+	outputBuffer int
+
+	// retryAttempts и retryBackoff — повторы transform (см. WithRetry);
+	// при retryAttempts <= 1 повторов нет
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	lockThreads     bool
+	ramp            time.Duration
+
+	queueDepth  int // 0 — без очередей перед воркерами
+	queuePolicy DropPolicy
+
+	// latencyBuckets — границы гистограммы времени обработки; nil, если
+	// гистограмма не нужна
+	latencyBuckets []time.Duration
+
+	progressInterval time.Duration
+	progress         func(Result)
+
+	sink Sink // nil — числа с выхода только подсчитываются
+
+	// forward — пересылка чисел воркером без WithTransform; тесты
+	// подменяют её, чтобы изобразить зависший воркер
+	forward func(ctx context.Context, in <-chan int64, out chan<- int64, delay time.Duration, hist func(time.Duration))
+}
+
+// WithObserver добавляет наблюдателя o. Без наблюдателей Run не тратит
+// время на уведомления.
+func WithObserver(o Observer) Option {
+	return func(c *config) {
+		c.observers = append(c.observers, o)
+	}
+}
+
+// WithCollectorFunc добавляет функцию fn, которую сборщик вызывает для
+// каждого числа, пересланного из канала воркера с индексом workerID в
+// результирующий канал, — например, чтобы строить гистограммы по воркерам.
+// fn вызывается из горутины сборщика этого воркера, то есть параллельно для
+// разных воркеров; количество вызовов для каждого workerID к возврату Run
+// равно PerChannel[workerID]. Функция fn генератора при этом не меняется.
+func WithCollectorFunc(fn func(workerID int, v int64)) Option {
+	return WithObserver(collectorFunc(fn))
+}
+
+// collectorFunc — наблюдатель, вызывающий функцию для каждого
+// пересланного сборщиком числа.
+type collectorFunc func(workerID int, v int64)
+
+func (f collectorFunc) Generated(int64)                 {}
+func (f collectorFunc) Processed(workerID int, v int64) { f(workerID, v) }
+
+// WithDelay задаёт паузу воркеров после пересылки каждого числа (см.
+// WorkerWithDelay); по умолчанию — DefaultDelay.
+func WithDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.delay = d
+	}
+}
+
+// WithDistributor включает распределение чисел через диспетчер (см.
+// FanOutWith) вместо общего входного канала, за чтение из которого
+// соревнуются воркеры; последнее остаётся поведением по умолчанию.
+// Функция newDistributor получает счётчики по каналам воркеров, длина
+// которых равна количеству воркеров, поэтому ей подходит NewLeastLoaded.
+func WithDistributor(newDistributor func(amounts []int64) Distributor) Option {
+	return func(c *config) {
+		c.distributor = newDistributor
+	}
+}
+
+// WithInputBuffer задаёт ёмкость входного канала между генератором и
+// воркерами; по умолчанию канал небуферизованный. Чем больше буфер, тем
+// сильнее разбивка по каналам смещается в пользу воркеров, которые
+// просыпаются первыми: они успевают забрать из буфера несколько чисел
+// подряд. Отрицательный size считается нулём.
+func WithInputBuffer(size int) Option {
+	return func(c *config) {
+		c.inputBuffer = max(size, 0)
+	}
+}
+
+// WithOutputBuffer задаёт ёмкость результирующего канала, в который сборщик
+// пересылает числа из каналов воркеров; по умолчанию она равна количеству
+// воркеров. Маленький буфер чаще блокирует горутины сборщика до чтения
+// результата, большой — увеличивает задержку между воркером и итогами.
+// Отрицательный size восстанавливает значение по умолчанию.
+func WithOutputBuffer(size int) Option {
+	return func(c *config) {
+		c.outputBuffer = size
+	}
+}
+
+// WithTransform включает преобразование чисел в воркерах (см.
+// WorkerDeadLetter): числа, для которых transform вернул ошибку, не попадают
+// в результирующий канал, а учитываются в Result.DeadLettered и
+// Result.DeadLetteredSum. Проверка сумм в Result.Verify рассчитана на
+// transform, который не меняет принятые числа, а только отбраковывает их.
+// WithTransform отменяет повторы, заданные WithRetry.
+func WithTransform(transform func(int64) (int64, error)) Option {
+	return func(c *config) {
+		c.transform = transform
+		c.retryAttempts = 0
+	}
+}
+
+// WithIdleTimeout останавливает генерацию, если генератор не отправил ни
+// одного числа за окно d, — например, когда GeneratorFromReader ждёт
+// данных, которые не приходят. Простой обнаруживается не раньше чем через d
+// и не позже чем через 2*d после последнего числа; дальше конвейер
+// дочитывается как при отмене ctx. При d <= 0 ограничения нет.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.idleTimeout = d
+	}
+}
+
+// WithShutdownTimeout ограничивает время, которое после отмены контекста
+// Run отводит конвейеру на то, чтобы дочитать уже отправленные числа. Если
+// за d конвейер не завершился, Run возвращает *ShutdownError с первой
+// незавершившейся стадией вместо того, чтобы зависнуть навсегда; горутины
+// зависшей стадии при этом продолжают работать. При d <= 0 ограничения
+// нет.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithWorkerQueue ставит перед каждым воркером собственную очередь
+// глубиной depth с политикой переполнения policy (см. WorkerQueue). Числа
+// раздаёт горутина-диспетчер: по кругу либо, если задан WithDistributor,
+// выбранным распределителем. Отброшенные числа учитываются в
+// Result.Dropped и Result.DroppedSum. При depth <= 0 очередей нет.
+func WithWorkerQueue(depth int, policy DropPolicy) Option {
+	return func(c *config) {
+		c.queueDepth = max(depth, 0)
+		c.queuePolicy = policy
+	}
+}
+
+// WithLockThreads закрепляет горутину каждого воркера за отдельным потоком
+// ОС через runtime.LockOSThread на всё время её работы. Это лишь подсказка
+// планировщику, а не привязка к ядру процессора; она нужна в основном для
+// замеров влияния планирования на задержки и равномерность разбивки.
+func WithLockThreads() Option {
+	return func(c *config) {
+		c.lockThreads = true
+	}
+}
+
+// WithLatencyHistogram включает гистограмму времени обработки чисел
+// воркерами с границами корзин bounds (см. NewLatencyHistogram; при пустом
+// bounds — DefaultLatencyBuckets). Время измеряется так же, как в
+// WorkerTimed, а гистограмма попадает в Result.Latency.
+func WithLatencyHistogram(bounds ...time.Duration) Option {
+	return func(c *config) {
+		c.latencyBuckets = append([]time.Duration{}, bounds...)
+	}
+}
+
+// WithProgress включает периодический отчёт о ходе работы: каждые interval
+// Run вызывает report с промежуточным Result, в котором заполнены
+// InputCount, InputSum и PerChannel. Отчёты прекращаются при отмене
+// контекста Run и в любом случае до того, как Run вернёт итоговый
+// результат. При interval <= 0 или report == nil отчёта нет.
+func WithProgress(interval time.Duration, report func(Result)) Option {
+	return func(c *config) {
+		c.progressInterval = interval
+		c.progress = report
+	}
+}
+
+// newConfig применяет opts к параметрам по умолчанию.
+func newConfig(opts []Option) *config {
+	c := &config{delay: DefaultDelay, outputBuffer: -1, forward: forward[int64]}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// observerFunc — наблюдатель, которому нужны только сгенерированные числа.
+type observerFunc func(v int64)
+
+func (f observerFunc) Generated(v int64)  { f(v) }
+func (observerFunc) Processed(int, int64) {}
+
+// WithSink направляет числа с выхода конвейера в sink. Итоги Result
+// считаются как обычно. Если Write возвращает ошибку, генерация
+// останавливается с причиной ErrSink, оставшиеся числа дочитываются без
+// записи в sink, а Run возвращает ошибку вместе с итогами. Close
+// вызывается, когда результирующий канал закрыт.
+func WithSink(sink Sink) Option {
+	return func(c *config) {
+		c.sink = sink
+	}
+}
+
+// WithRamp запускает воркеры не сразу, а равномерно в течение ramp: воркер
+// с индексом i начинает читать числа через i*ramp/numOut после запуска
+// конвейера, а генератор начинает работу сразу. Если генератор
+// завершается раньше — из-за отмены ctx или потому что числа кончились, —
+// оставшиеся воркеры запускаются немедленно, чтобы дочитать конвейер. При
+// ramp <= 0 все воркеры запускаются сразу.
+//
+// Разгон нагляднее всего с общим входным каналом: при распределении по
+// очереди (WithDistributor, WithWorkerQueue) ещё не запущенный воркер
+// задерживает и остальных.
+func WithRamp(ramp time.Duration) Option {
+	return func(c *config) {
+		c.ramp = ramp
+	}
+}
@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefaultReorderSize — размер буфера переупорядочивания OrderedFanIn по
+// умолчанию.
+const DefaultReorderSize = 64
+
+// OrderedFanIn объединяет каналы channels, как FanIn, но восстанавливает
+// порядок генерации. Функция index возвращает порядковый номер значения в
+// сгенерированной последовательности (если index равна nil, номером считается
+// само значение, что подходит для Generator), first — номер первого значения.
+//
+// Пришедшие раньше времени значения ждут своей очереди в буфере
+// переупорядочивания не больше чем из size элементов (при size <= 0
+// используется DefaultReorderSize). Если буфер переполнен, OrderedFanIn
+// перестаёт ждать недостающий номер и продолжает с наименьшего из
+// ожидающих; опоздавшее значение затем отправляется сразу, вне порядка.
+// Поэтому строгий порядок гарантирован, только пока разброс между воркерами
+// меньше size, — для FanOut это примерно количество воркеров. Если вывод
+// вне порядка недопустим, подойдёт OrderedFanInGap.
+func OrderedFanIn(first int64, size int, index func(int64) int64, channels ...<-chan int64) <-chan int64 {
+	if size <= 0 {
+		size = DefaultReorderSize
+	}
+	if index == nil {
+		index = func(v int64) int64 { return v }
+	}
+
+	merged := FanIn(channels...)
+	out := make(chan int64)
+
+	go func() {
+		defer close(out)
+
+		// pending — значения, пришедшие раньше своей очереди, по номерам
+		pending := make(map[int64]int64, size)
+		next := first
+
+		flush := func() {
+			for {
+				v, ok := pending[next]
+				if !ok {
+					return
+				}
+				delete(pending, next)
+				out <- v
+				next++
+			}
+		}
+
+		for v := range merged {
+			k := index(v)
+			if k < next {
+				// опоздавшее значение, которое уже перестали ждать
+				out <- v
+				continue
+			}
+			pending[k] = v
+			flush()
+			if len(pending) > size {
+				next = slices.Min(keys(pending))
+				flush()
+			}
+		}
+
+		// входные каналы закрыты: отдаём остаток по возрастанию номеров
+		rest := keys(pending)
+		slices.Sort(rest)
+		for _, k := range rest {
+			out <- pending[k]
+		}
+	}()
+
+	return out
+}
+
+// ErrGapExceeded сообщает, что значение OrderedFanInGap опередило
+// ожидаемый номер больше чем на maxGap.
+var ErrGapExceeded = errors.New("разрыв порядка превысил предел")
+
+// GapPolicy — правило OrderedFanInGap на случай, когда значение опережает
+// ожидаемый номер больше чем на maxGap.
+type GapPolicy int
+
+const (
+	// GapBlock — не читать канал опередившего значения, пока недостающие
+	// номера не дойдут.
+	GapBlock GapPolicy = iota
+	// GapError — сообщить ErrGapExceeded и перестать ждать недостающие
+	// номера.
+	GapError
+)
+
+// OrderedFanInGap работает как OrderedFanIn, но предел памяти задаётся
+// разрывом номеров: значение, номер которого больше ожидаемого больше чем
+// на maxGap, сразу в буфер не попадает, поэтому в нём никогда не бывает
+// больше maxGap+1 значений (при maxGap <= 0 используется
+// DefaultReorderSize). Так зависший воркер не может раздуть буфер.
+//
+// С GapBlock горутина канала, принёсшего такое значение, ждёт, пока
+// разрыв не сократится, и перестаёт читать свой канал, то есть поток
+// притормаживается до отстающего воркера; порядок при этом строгий. С
+// GapError OrderedFanInGap перестаёт ждать недостающие номера: отправляет
+// по порядку ожидающие значения, которые иначе вышли бы за предел, и
+// продолжает так, чтобы новое значение уложилось в maxGap. Пропущенные
+// номера, если они всё же придут, отправляются сразу, вне порядка, как в
+// OrderedFanIn; значения не теряются. О каждом таком пропуске в errs
+// отправляется ошибка ErrGapExceeded с номерами и общим числом пропусков.
+// Отправка не блокирует: если errs равен nil или его никто не читает,
+// ошибка отбрасывается, и о ней можно узнать по счётчику в следующей.
+// Канал errs OrderedFanInGap не закрывает.
+//
+// В обоих случаях out закрывается, когда закрыты и прочитаны все channels.
+// Предполагается, что номера в каждом из channels возрастают, как у
+// воркеров FanOut; иначе с GapBlock возможна взаимная блокировка.
+func OrderedFanInGap(first, maxGap int64, policy GapPolicy, index func(int64) int64, errs chan<- error, channels ...<-chan int64) <-chan int64 {
+	if maxGap <= 0 {
+		maxGap = DefaultReorderSize
+	}
+	if index == nil {
+		index = func(v int64) int64 { return v }
+	}
+
+	// next — ожидаемый номер; его меняет горутина упорядочивания, а с
+	// GapBlock читают горутины каналов, поэтому он защищён mu
+	var mu sync.Mutex
+	advanced := sync.NewCond(&mu)
+	next := first
+
+	merged := make(chan int64)
+	var wg sync.WaitGroup
+	for _, in := range channels {
+		wg.Add(1)
+		go func(in <-chan int64) {
+			defer wg.Done()
+			for v := range in {
+				if policy == GapBlock {
+					k := index(v)
+					mu.Lock()
+					for k-next > maxGap {
+						advanced.Wait()
+					}
+					mu.Unlock()
+				}
+				merged <- v
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	out := make(chan int64)
+	go func() {
+		defer close(out)
+
+		// current — копия next, которой владеет эта горутина
+		pending := make(map[int64]int64)
+		current := first
+		var skips int64
+		for v := range merged {
+			k := index(v)
+			if k < current {
+				// пропущенный или повторный номер: порядок уже не
+				// восстановить
+				out <- v
+				continue
+			}
+
+			if k-current > maxGap {
+				// только GapError: с GapBlock такие значения не приходят
+				skips++
+				floor := k - maxGap
+				reportGap(errs, fmt.Errorf("%w: ожидается номер %d, пришёл %d, предел %d, пропусков всего %d",
+					ErrGapExceeded, current, k, maxGap, skips))
+				below := slices.DeleteFunc(keys(pending), func(p int64) bool { return p >= floor })
+				slices.Sort(below)
+				for _, p := range below {
+					out <- pending[p]
+					delete(pending, p)
+				}
+				current = floor
+			}
+
+			pending[k] = v
+			for {
+				w, ok := pending[current]
+				if !ok {
+					break
+				}
+				delete(pending, current)
+				out <- w
+				current++
+			}
+
+			mu.Lock()
+			next = current
+			advanced.Broadcast()
+			mu.Unlock()
+		}
+
+		rest := keys(pending)
+		slices.Sort(rest)
+		for _, k := range rest {
+			out <- pending[k]
+		}
+	}()
+
+	return out
+}
+
+// reportGap отправляет err в errs, если его сейчас читают; иначе ошибка
+// отбрасывается.
+func reportGap(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
+
+// keys возвращает номера значений, ожидающих в буфере переупорядочивания.
+func keys(pending map[int64]int64) []int64 {
+	ks := make([]int64, 0, len(pending))
+	for k := range pending {
+		ks = append(ks, k)
+	}
+	return ks
+}
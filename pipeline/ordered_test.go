@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stalledGapRun прогоняет через OrderedFanInGap сценарий с зависшим
+// воркером: a держит 1, пока b отправляет 2..10, затем a отправляет 1, а b —
+// 11..100. Возвращает всё, что пришло в out.
+func stalledGapRun(t *testing.T, policy GapPolicy, errs chan<- error) []int64 {
+	t.Helper()
+	a := make(chan int64)
+	b := make(chan int64)
+	out := OrderedFanInGap(1, 4, policy, nil, errs, a, b)
+
+	got := make(chan []int64)
+	go func() {
+		var values []int64
+		for v := range out {
+			values = append(values, v)
+		}
+		got <- values
+	}()
+
+	go func() {
+		for i := int64(2); i <= 10; i++ {
+			b <- i
+		}
+		a <- 1
+		close(a)
+		for i := int64(11); i <= 100; i++ {
+			b <- i
+		}
+		close(b)
+	}()
+
+	select {
+	case values := <-got:
+		return values
+	case <-time.After(5 * time.Second):
+		t.Fatal("OrderedFanInGap не завершился")
+		return nil
+	}
+}
+
+func TestOrderedFanInGapErrorSkipsStalledIndex(t *testing.T) {
+	errs := make(chan error, 100)
+	got := stalledGapRun(t, GapError, errs)
+	close(errs)
+
+	if len(got) != 100 {
+		t.Fatalf("получено %d значений, ожидалось 100: %v", len(got), got)
+	}
+	sorted := slices.Clone(got)
+	slices.Sort(sorted)
+	for i, v := range sorted {
+		if v != int64(i+1) {
+			t.Fatalf("значения потеряны или повторены: %v", got)
+		}
+	}
+
+	// пропущенная 1 приходит вне порядка, а 11..100 идут строго по порядку
+	rest := slices.DeleteFunc(slices.Clone(got), func(v int64) bool { return v == 1 })
+	tail := rest[slices.Index(rest, 11):]
+	for i := 1; i < len(tail); i++ {
+		if tail[i] != tail[i-1]+1 {
+			t.Fatalf("хвост вне порядка: %v", tail)
+		}
+	}
+
+	var reported int
+	for err := range errs {
+		if !errors.Is(err, ErrGapExceeded) {
+			t.Errorf("ошибка %v, ожидалась ErrGapExceeded", err)
+		}
+		reported++
+	}
+	if reported == 0 {
+		t.Error("пропуск номера не сообщён")
+	}
+}
+
+func TestOrderedFanInGapErrorNilErrs(t *testing.T) {
+	// без читателя ошибок канал воркера не должен зависать на отправке
+	if got := stalledGapRun(t, GapError, nil); len(got) != 100 {
+		t.Fatalf("получено %d значений, ожидалось 100", len(got))
+	}
+}
+
+func TestOrderedFanInGapBlockBoundsBuffer(t *testing.T) {
+	const maxGap = 4
+	a := make(chan int64)
+	b := make(chan int64)
+	out := OrderedFanInGap(1, maxGap, GapBlock, nil, nil, a, b)
+
+	var sent atomic.Int64
+	go func() {
+		for i := int64(2); i <= 100; i++ {
+			b <- i
+			sent.Add(1)
+		}
+		close(b)
+	}()
+
+	// a держит 1: b должен упереться в предел, а не заполнять буфер
+	time.Sleep(50 * time.Millisecond)
+	if n := sent.Load(); n > maxGap+1 {
+		t.Fatalf("при зависшем воркере принято %d значений, предел %d", n, maxGap+1)
+	}
+
+	a <- 1
+	close(a)
+	var got []int64
+	for v := range out {
+		got = append(got, v)
+	}
+	for i, v := range got {
+		if v != int64(i+1) {
+			t.Fatalf("порядок нарушен: %v", got)
+		}
+	}
+	if len(got) != 100 {
+		t.Fatalf("получено %d значений, ожидалось 100", len(got))
+	}
+}
+
+func TestOrderedFanInRestoresOrder(t *testing.T) {
+	const n = 1000
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int64)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	// буфер не меньше всей последовательности, так что ожидание
+	// недостающего номера никогда не прерывается
+	out := OrderedFanIn(1, n, nil, FanOut(ctx, in, 4)...)
+
+	var count, prev int64
+	for v := range out {
+		if v <= prev {
+			t.Fatalf("после %d пришло %d", prev, v)
+		}
+		prev = v
+		count++
+	}
+	if count != n {
+		t.Fatalf("получено %d чисел, ожидалось %d", count, n)
+	}
+}
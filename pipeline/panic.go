@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+)
+
+// PanicError — ошибка, в которую превращается паника в пользовательском
+// коде, вызванном конвейером: в преобразовании воркера или в наблюдателе.
+type PanicError struct {
+	Value any // значение, переданное в panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("паника: %v", e.Value)
+}
+
+// safeTransform вызывает transform(v) и превращает панику в *PanicError,
+// чтобы число ушло по пути ошибок, а воркер продолжил работу и закрыл свой
+// выходной канал как обычно.
+func safeTransform(ctx context.Context, transform func(int64) (int64, error), v int64) (res int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r}
+			logFor(ctx).ErrorContext(ctx, "паника в преобразовании числа", "value", v, "error", err)
+		}
+	}()
+	return transform(v)
+}
+
+// safeProcessed вызывает processed(worker, v) и журналирует панику вместо
+// того, чтобы уронить горутину сборщика: число к этому моменту уже
+// переслано, а без сборщика канал воркера остался бы недочитанным.
+func safeProcessed(ctx context.Context, processed func(worker int, v int64), worker int, v int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			logFor(ctx).ErrorContext(ctx, "паника в наблюдателе", "worker", worker, "value", v, "error", &PanicError{Value: r})
+		}
+	}()
+	processed(worker, v)
+}
+
+// safeGenerated вызывает o.Generated(v) и журналирует панику, чтобы
+// наблюдатель не уронил горутину генератора: число к этому моменту уже
+// отправлено и учтено во входных итогах.
+func safeGenerated(ctx context.Context, o Observer, v int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			logFor(ctx).ErrorContext(ctx, "паника в наблюдателе", "value", v, "error", &PanicError{Value: r})
+		}
+	}()
+	o.Generated(v)
+}
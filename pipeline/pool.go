@@ -0,0 +1,153 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WorkerPool — набор воркеров, читающих из общего канала и пишущих в общий
+// выходной канал, размер которого можно менять во время работы через
+// Resize. Каждый воркер работает как Worker: пересылает число и делает
+// паузу DefaultDelay.
+//
+// Выходной канал закрывается, когда закрывается входной канал или
+// отменяется ctx и все воркеры завершились; после этого Resize ничего не
+// делает.
+type WorkerPool struct {
+	ctx context.Context
+	in  <-chan int64
+	out chan int64
+
+	mu      sync.Mutex
+	workers []*poolWorker // работающие воркеры в порядке запуска
+	running int           // запущенные и ещё не завершившиеся горутины
+	done    bool          // in закрыт или ctx отменён
+
+	processed int64 // числа, пересланные всеми воркерами, включая удалённые
+}
+
+// poolWorker — воркер пула и его счётчик пересланных чисел.
+type poolWorker struct {
+	stop  chan struct{}
+	count int64
+}
+
+// NewWorkerPool запускает пул из n воркеров, читающих из in.
+func NewWorkerPool(ctx context.Context, in <-chan int64, n int) *WorkerPool {
+	p := &WorkerPool{
+		ctx: ctx,
+		in:  in,
+		out: make(chan int64),
+	}
+	p.Resize(n)
+	return p
+}
+
+// Out возвращает выходной канал пула.
+func (p *WorkerPool) Out() <-chan int64 {
+	return p.out
+}
+
+// Size возвращает текущее количество воркеров.
+func (p *WorkerPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.workers)
+}
+
+// Resize меняет количество воркеров на n (отрицательное n считается
+// нулём). Лишние воркеры удаляются, начиная с последних запущенных: каждый
+// из них дописывает текущее число в выходной канал и только потом
+// завершается, поэтому числа при уменьшении пула не теряются. При n == 0
+// пул приостанавливается, но выходной канал не закрывается.
+func (p *WorkerPool) Resize(n int) {
+	n = max(n, 0)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+
+	for len(p.workers) < n {
+		w := &poolWorker{stop: make(chan struct{})}
+		p.workers = append(p.workers, w)
+		p.running++
+		go p.work(w)
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		w := p.workers[last]
+		p.workers = p.workers[:last]
+		close(w.stop)
+	}
+}
+
+// Amounts возвращает количество чисел, пересланных каждым из работающих
+// воркеров, в порядке их запуска; длина слайса равна Size.
+func (p *WorkerPool) Amounts() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	amounts := make([]int64, len(p.workers))
+	for i, w := range p.workers {
+		amounts[i] = atomic.LoadInt64(&w.count)
+	}
+	return amounts
+}
+
+// Processed возвращает количество чисел, пересланных всеми воркерами пула,
+// включая уже удалённые.
+func (p *WorkerPool) Processed() int64 {
+	return atomic.LoadInt64(&p.processed)
+}
+
+// work — тело воркера w.
+func (p *WorkerPool) work(w *poolWorker) {
+	defer p.exit()
+
+	for {
+		var v int64
+		var ok bool
+		select {
+		case <-w.stop:
+			return
+		case <-p.ctx.Done():
+			p.finish()
+			return
+		case v, ok = <-p.in:
+		}
+		if !ok {
+			p.finish()
+			return
+		}
+
+		if !sendCtx(p.ctx, p.out, v) {
+			p.finish()
+			return
+		}
+		atomic.AddInt64(&w.count, 1)
+		atomic.AddInt64(&p.processed, 1)
+		clk().Sleep(DefaultDelay)
+	}
+}
+
+// finish отмечает, что входные числа кончились.
+func (p *WorkerPool) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = true
+}
+
+// exit учитывает завершение горутины воркера и закрывает выходной канал,
+// когда числа кончились и завершилась последняя горутина.
+func (p *WorkerPool) exit() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running--
+	if p.done && p.running == 0 {
+		close(p.out)
+	}
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWorkerPoolResizeLosesNothing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 500
+	in := make(chan int64)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	pool := NewWorkerPool(ctx, in, 2)
+
+	// размер меняется посреди потока: пул растёт, сжимается, встаёт на паузу
+	// и снова запускается
+	resize := func(size int) {
+		pool.Resize(size)
+		if got := pool.Size(); got != size {
+			t.Fatalf("после Resize(%d) размер пула %d", size, got)
+		}
+	}
+	seen := make(map[int64]bool, n)
+	received := 0
+	for v := range pool.Out() {
+		if seen[v] {
+			t.Fatalf("число %d получено дважды", v)
+		}
+		seen[v] = true
+		received++
+		switch received {
+		case 100:
+			resize(6)
+		case 200:
+			resize(1)
+		case 300:
+			resize(0)
+			resize(3)
+		}
+	}
+	if received != n {
+		t.Fatalf("получено %d чисел, ожидалось %d", received, n)
+	}
+	if pool.Processed() != n {
+		t.Fatalf("Processed() = %d, ожидалось %d", pool.Processed(), n)
+	}
+}
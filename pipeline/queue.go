@@ -0,0 +1,160 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy — правило WorkerQueue на случай, когда очередь заполнена.
+type DropPolicy int
+
+const (
+	// DropBlock — ждать, пока в очереди освободится место; числа не
+	// теряются.
+	DropBlock DropPolicy = iota
+	// DropNewest — отбросить поступающее число.
+	DropNewest
+	// DropOldest — отбросить самое старое число очереди и поставить
+	// поступающее.
+	DropOldest
+)
+
+// String возвращает название политики в том виде, в каком его принимает
+// ParseDropPolicy.
+func (p DropPolicy) String() string {
+	switch p {
+	case DropBlock:
+		return "block"
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	default:
+		return fmt.Sprintf("DropPolicy(%d)", int(p))
+	}
+}
+
+// ParseDropPolicy разбирает название политики: block, drop-newest или
+// drop-oldest.
+func ParseDropPolicy(s string) (DropPolicy, error) {
+	for _, p := range []DropPolicy{DropBlock, DropNewest, DropOldest} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("неизвестная политика переполнения очереди %q", s)
+}
+
+// WorkerQueue — ограниченная очередь перед воркером, которая при
+// переполнении поступает согласно своей DropPolicy. Отброшенные числа
+// подсчитываются.
+type WorkerQueue struct {
+	ch      chan int64
+	policy  DropPolicy
+	once    sync.Once
+	dropped int64
+	sum     int64
+}
+
+// NewWorkerQueue создаёт очередь глубиной depth с политикой policy. При
+// depth < 1 глубина равна 1.
+func NewWorkerQueue(depth int, policy DropPolicy) *WorkerQueue {
+	return &WorkerQueue{
+		ch:     make(chan int64, max(depth, 1)),
+		policy: policy,
+	}
+}
+
+// Push ставит v в очередь согласно политике и возвращает false, только если
+// ctx отменился раньше, чем число удалось поставить или отбросить.
+// Отброшенное число не считается ошибкой: Push возвращает true.
+func (q *WorkerQueue) Push(ctx context.Context, v int64) bool {
+	switch q.policy {
+	case DropNewest:
+		select {
+		case q.ch <- v:
+		default:
+			q.drop(v)
+		}
+		return true
+	case DropOldest:
+		for {
+			select {
+			case q.ch <- v:
+				return true
+			default:
+			}
+			// очередь полна: освобождаем место, если воркер не успел
+			// забрать число раньше нас, и пробуем снова
+			select {
+			case old := <-q.ch:
+				q.drop(old)
+			default:
+			}
+		}
+	default:
+		return sendCtx(ctx, q.ch, v)
+	}
+}
+
+// drop учитывает отброшенное число v.
+func (q *WorkerQueue) drop(v int64) {
+	atomic.AddInt64(&q.dropped, 1)
+	atomic.AddInt64(&q.sum, v)
+}
+
+// Out возвращает канал, из которого воркер читает очередь.
+func (q *WorkerQueue) Out() <-chan int64 {
+	return q.ch
+}
+
+// Close закрывает очередь; воркер дочитает оставшиеся числа. Push нельзя
+// вызывать после Close; повторный вызов Close безопасен.
+func (q *WorkerQueue) Close() {
+	q.once.Do(func() { close(q.ch) })
+}
+
+// Dropped возвращает количество отброшенных чисел.
+func (q *WorkerQueue) Dropped() int64 {
+	return atomic.LoadInt64(&q.dropped)
+}
+
+// DroppedSum возвращает сумму отброшенных чисел.
+func (q *WorkerQueue) DroppedSum() int64 {
+	return atomic.LoadInt64(&q.sum)
+}
+
+// dispatchQueues — вариант dispatch, в котором перед каждым воркером стоит
+// своя очередь из queues: горутина-диспетчер ставит каждое число из in в
+// очередь queues[next()] и закрывает все очереди, когда закрывается in или
+// отменяется ctx.
+func dispatchQueues(ctx context.Context, in <-chan int64, queues []*WorkerQueue, next func() int, work workFunc) []<-chan int64 {
+	outs := make([]<-chan int64, len(queues))
+	for i, q := range queues {
+		out := make(chan int64)
+		outs[i] = out
+		go work(ctx, i, q.Out(), out)
+	}
+
+	go func() {
+		defer func() {
+			for _, q := range queues {
+				q.Close()
+			}
+		}()
+
+		for {
+			v, ok := recvCtx(ctx, in)
+			if !ok {
+				return
+			}
+			if !queues[next()].Push(ctx, v) {
+				return
+			}
+		}
+	}()
+
+	return outs
+}
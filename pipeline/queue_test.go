@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fillQueue ставит в очередь q числа 1..n без читателя.
+func fillQueue(t *testing.T, q *WorkerQueue, n int64) {
+	t.Helper()
+	for v := int64(1); v <= n; v++ {
+		if !q.Push(context.Background(), v) {
+			t.Fatalf("Push(%d) вернул false", v)
+		}
+	}
+	q.Close()
+}
+
+// drainQueue дочитывает закрытую очередь q.
+func drainQueue(q *WorkerQueue) []int64 {
+	var got []int64
+	for v := range q.Out() {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestWorkerQueueDropNewest(t *testing.T) {
+	q := NewWorkerQueue(4, DropNewest)
+	fillQueue(t, q, 10)
+	if q.Dropped() != 6 || q.DroppedSum() != 5+6+7+8+9+10 {
+		t.Fatalf("отброшено %d на сумму %d, ожидалось 6 на сумму 45", q.Dropped(), q.DroppedSum())
+	}
+	got := drainQueue(q)
+	for i, v := range []int64{1, 2, 3, 4} {
+		if i >= len(got) || got[i] != v {
+			t.Fatalf("в очереди %v, ожидалось [1 2 3 4]", got)
+		}
+	}
+}
+
+func TestWorkerQueueDropOldest(t *testing.T) {
+	q := NewWorkerQueue(4, DropOldest)
+	fillQueue(t, q, 10)
+	if q.Dropped() != 6 || q.DroppedSum() != 1+2+3+4+5+6 {
+		t.Fatalf("отброшено %d на сумму %d, ожидалось 6 на сумму 21", q.Dropped(), q.DroppedSum())
+	}
+	got := drainQueue(q)
+	for i, v := range []int64{7, 8, 9, 10} {
+		if i >= len(got) || got[i] != v {
+			t.Fatalf("в очереди %v, ожидалось [7 8 9 10]", got)
+		}
+	}
+}
+
+func TestWorkerQueueDropBlock(t *testing.T) {
+	q := NewWorkerQueue(4, DropBlock)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	for v := int64(1); v <= 4; v++ {
+		if !q.Push(ctx, v) {
+			t.Fatalf("Push(%d) вернул false при свободном месте", v)
+		}
+	}
+	if q.Push(ctx, 5) {
+		t.Fatal("Push в полную очередь вернул true до отмены контекста")
+	}
+	if q.Dropped() != 0 || q.DroppedSum() != 0 {
+		t.Fatalf("отброшено %d на сумму %d, ожидалось 0", q.Dropped(), q.DroppedSum())
+	}
+}
+
+// Быстрый генератор и медленные воркеры: числа, не уместившиеся в очереди,
+// должны попасть в Result.Dropped так, чтобы итоги сходились.
+func TestRunWorkerQueuePolicies(t *testing.T) {
+	for _, policy := range []DropPolicy{DropBlock, DropNewest, DropOldest} {
+		t.Run(policy.String(), func(t *testing.T) {
+			result, err := RunBounded(context.Background(), 2, 200,
+				WithDelay(time.Millisecond), WithWorkerQueue(2, policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := result.Verify(); err != nil {
+				t.Fatalf("итоги не сходятся: %v", err)
+			}
+			if result.InputCount != 200 {
+				t.Fatalf("сгенерировано %d, ожидалось 200", result.InputCount)
+			}
+			if policy == DropBlock && result.Dropped != 0 {
+				t.Fatalf("при block отброшено %d чисел", result.Dropped)
+			}
+			if policy != DropBlock && result.Dropped == 0 {
+				t.Fatalf("при %s ничего не отброшено", policy)
+			}
+			if result.OutputCount+result.Dropped != result.InputCount-result.DeadLettered {
+				t.Fatalf("доставлено %d, отброшено %d из %d", result.OutputCount, result.Dropped, result.InputCount)
+			}
+		})
+	}
+}
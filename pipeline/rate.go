@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRateWindow и DefaultRateSlots — окно RateMeter по умолчанию и
+// число ячеек, на которые оно делится.
+const (
+	DefaultRateWindow = 5 * time.Second
+	DefaultRateSlots  = 50
+)
+
+// RateMeter оценивает текущую скорость потока чисел за скользящее окно.
+// Окно делится на ячейки фиксированной длины, уложенные в кольцевой буфер:
+// Add увеличивает счётчик ячейки текущего момента, а ячейки, выпавшие из
+// окна, переиспользуются. Поэтому запись обходится одним захватом мьютекса
+// без выделения памяти и подходит для вызова на каждое число. RateMeter
+// реализует Observer и считает сгенерированные числа. Время берётся из
+// часов пакета (см. SetClock).
+type RateMeter struct {
+	mu     sync.Mutex
+	slot   time.Duration
+	start  time.Time
+	counts []int64
+	epochs []int64 // номер ячейки времени, которой принадлежит counts[i]
+}
+
+// NewRateMeter создаёт RateMeter с окном window, разделённым на slots
+// ячеек. При window <= 0 используется DefaultRateWindow, при slots < 1 —
+// DefaultRateSlots.
+func NewRateMeter(window time.Duration, slots int) *RateMeter {
+	if window <= 0 {
+		window = DefaultRateWindow
+	}
+	if slots < 1 {
+		slots = DefaultRateSlots
+	}
+	slot := window / time.Duration(slots)
+	if slot <= 0 {
+		slot = 1
+	}
+	m := &RateMeter{
+		slot:   slot,
+		start:  clk().Now(),
+		counts: make([]int64, slots),
+		epochs: make([]int64, slots),
+	}
+	for i := range m.epochs {
+		m.epochs[i] = -1
+	}
+	return m
+}
+
+// Add учитывает одно число. Подходит в качестве fn генератора.
+func (m *RateMeter) Add(int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	epoch := m.epoch(clk().Now())
+	i := int(epoch % int64(len(m.counts)))
+	if m.epochs[i] != epoch {
+		m.epochs[i] = epoch
+		m.counts[i] = 0
+	}
+	m.counts[i]++
+}
+
+// PerSecond возвращает число чисел в секунду за последнее окно. Пока с
+// создания RateMeter не прошло целое окно, скорость считается по
+// прошедшему времени.
+func (m *RateMeter) PerSecond() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := clk().Now()
+	epoch := m.epoch(now)
+	oldest := epoch - int64(len(m.counts)) + 1
+
+	var total int64
+	for i, e := range m.epochs {
+		if e >= oldest && e <= epoch {
+			total += m.counts[i]
+		}
+	}
+
+	// окно покрывает все полные ячейки и прошедшую часть текущей
+	elapsed := time.Duration(len(m.counts)-1)*m.slot + now.Sub(m.start) - time.Duration(epoch)*m.slot
+	if since := now.Sub(m.start); since < elapsed {
+		elapsed = since
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(total) / elapsed.Seconds()
+}
+
+// epoch возвращает номер ячейки времени, в которую попадает момент t.
+func (m *RateMeter) epoch(t time.Time) int64 {
+	d := t.Sub(m.start)
+	if d < 0 {
+		return 0
+	}
+	return int64(d / m.slot)
+}
+
+// Generated учитывает сгенерированное число.
+func (m *RateMeter) Generated(v int64) { m.Add(v) }
+
+// Processed ничего не делает: RateMeter измеряет скорость входа конвейера.
+func (m *RateMeter) Processed(int, int64) {}
@@ -0,0 +1,37 @@
+package pipeline
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateMeterConverges(t *testing.T) {
+	c := useFakeClock(t)
+	m := NewRateMeter(time.Second, 10)
+
+	// feed добавляет perTick чисел каждые 10ms в течение d
+	feed := func(perTick int, d time.Duration) {
+		for elapsed := time.Duration(0); elapsed < d; elapsed += 10 * time.Millisecond {
+			for i := 0; i < perTick; i++ {
+				m.Add(1)
+			}
+			c.Advance(10 * time.Millisecond)
+		}
+	}
+	check := func(want float64) {
+		t.Helper()
+		if got := m.PerSecond(); math.Abs(got-want) > want*0.05+1 {
+			t.Fatalf("скорость %.1f, ожидалось около %.1f", got, want)
+		}
+	}
+
+	feed(10, 500*time.Millisecond)
+	check(1000) // окно ещё не заполнено: считается по прошедшему времени
+	feed(10, 2*time.Second)
+	check(1000)
+	feed(2, 2*time.Second)
+	check(200)
+	c.Advance(2 * time.Second)
+	check(0)
+}
@@ -0,0 +1,40 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GeneratorFromReader читает из r целые числа, записанные по одному в
+// строке, и отправляет их в канал ch, вызывая fn после каждой успешной
+// записи, — так через конвейер можно повторно прогнать заранее записанную
+// нагрузку. Канал ch закрывается по достижении конца r или при отмене ctx.
+//
+// Пустые строки пропускаются. Строки, которые не удалось разобрать как
+// int64, тоже пропускаются и подсчитываются: их количество возвращается
+// первым значением. Ошибка возвращается только при ошибке чтения из r.
+func GeneratorFromReader(ctx context.Context, r io.Reader, ch chan<- int64, fn func(int64)) (int, error) {
+	defer closeOwned(ctx, ch, StageGenerator)
+
+	malformed := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			malformed++
+			continue
+		}
+		if !sendCtx(ctx, ch, v) {
+			return malformed, nil
+		}
+		fn(v)
+	}
+	return malformed, scanner.Err()
+}
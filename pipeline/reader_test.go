@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// Запуск, записанный Recorder, повторяется через GeneratorFromReader с теми
+// же итогами.
+func TestCaptureReplayRoundTrip(t *testing.T) {
+	var capture bytes.Buffer
+	rec := NewRecorder(&capture)
+	rng := rand.New(rand.NewPCG(7, 0))
+	original, err := RunWith(context.Background(), 3, func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorRandomN(ctx, ch, rng, 500, fn)
+	}, WithDelay(0), WithObserver(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := RunWith(context.Background(), 5, func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		if malformed, err := GeneratorFromReader(ctx, &capture, ch, fn); malformed != 0 || err != nil {
+			t.Errorf("чтение записи: пропущено строк %d, ошибка %v", malformed, err)
+		}
+	}, WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replayed.InputCount != original.InputCount || replayed.InputSum != original.InputSum ||
+		replayed.OutputSum != original.OutputSum || replayed.Min != original.Min || replayed.Max != original.Max {
+		t.Fatalf("повтор %+v не совпадает с записью %+v", replayed, original)
+	}
+}
+
+func TestGeneratorFromReaderSkipsMalformed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan int64)
+	result := make(chan int, 1)
+	go func() {
+		malformed, _ := GeneratorFromReader(ctx, strings.NewReader("1\n\nдва\n3\n 4 \nx5\n"), ch, func(int64) {})
+		result <- malformed
+	}()
+	if got := readAll(ch); len(got) != 3 || got[0] != 1 || got[1] != 3 || got[2] != 4 {
+		t.Fatalf("получено %v, ожидалось [1 3 4]", got)
+	}
+	if malformed := <-result; malformed != 2 {
+		t.Fatalf("пропущено строк %d, ожидалось 2", malformed)
+	}
+}
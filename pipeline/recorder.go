@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Recorder — наблюдатель, записывающий каждое сгенерированное число в w по
+// одному в строке, в формате, который читает GeneratorFromReader: запись
+// одного запуска можно затем повторно прогнать через конвейер. Запись
+// буферизуется, поэтому по окончании работы нужно вызвать Flush.
+type Recorder struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+// NewRecorder создаёт Recorder, пишущий в w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: bufio.NewWriter(w)}
+}
+
+// Generated записывает v отдельной строкой. После первой ошибки записи
+// следующие числа не записываются, а ошибку возвращает Flush.
+func (r *Recorder) Generated(v int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	r.buf = strconv.AppendInt(r.buf[:0], v, 10)
+	r.buf = append(r.buf, '\n')
+	_, r.err = r.w.Write(r.buf)
+}
+
+// Processed ничего не делает: Recorder записывает только вход конвейера.
+func (*Recorder) Processed(int, int64) {}
+
+// Flush дописывает буферизованные числа в w и возвращает первую ошибку
+// записи, если она была.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.w.Flush()
+	return r.err
+}
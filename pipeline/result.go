@@ -0,0 +1,351 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Result содержит итоговую статистику одного запуска конвейера.
+//
+// Для результата Run равенство InputCount == OutputCount + DeadLettered +
+// Dropped — жёсткий инвариант, а не следствие удачных таймингов: каждое
+// число, отправленное генератором, доходит до результирующего канала или
+// учитывается как недоставленное или отброшенное до того, как Run вернёт
+// результат (см. Run). Без WithTransform недоставленных чисел нет, а без
+// WithWorkerQueue — отброшенных.
+type Result struct {
+	InputCount  int64   `json:"input_count"`  // количество сгенерированных чисел
+	InputSum    int64   `json:"input_sum"`    // сумма сгенерированных чисел
+	OutputCount int64   `json:"output_count"` // количество чисел результирующего канала
+	OutputSum   int64   `json:"output_sum"`   // сумма чисел результирующего канала
+	PerChannel  []int64 `json:"per_channel"`  // количество чисел, прошедших через каждый канал воркера
+
+	DeadLettered    int64 `json:"dead_lettered"`     // количество недоставленных чисел
+	DeadLetteredSum int64 `json:"dead_lettered_sum"` // сумма недоставленных чисел
+
+	Dropped    int64 `json:"dropped"`     // количество чисел, отброшенных очередями воркеров
+	DroppedSum int64 `json:"dropped_sum"` // сумма чисел, отброшенных очередями воркеров
+
+	Min   int64 `json:"min"`   // наименьшее число результирующего канала; 0, если Empty
+	Max   int64 `json:"max"`   // наибольшее число результирующего канала; 0, если Empty
+	Empty bool  `json:"empty"` // true, если в результирующий канал не попало ни одного числа
+
+	// Overflow — сумма на входе или выходе хотя бы раз вышла за пределы
+	// int64. Суммы тогда равны истинным по модулю 2^64 и по-прежнему
+	// сравнимы между собой, но не как абсолютные значения.
+	Overflow bool `json:"overflow"`
+
+	StopReason StopReason `json:"stop_reason"` // почему остановилась генерация
+	// StopCause — название причины отмены генерации по context.Cause:
+	// deadline, signal, idle, overflow, sink, canceled или count
+	StopCause string `json:"stop_cause"`
+
+	// GenBlockedTotal и CollectBlockedTotal — суммарное время, которое
+	// генератор и горутины сборщика ждали получателя при отправке; большое
+	// значение указывает на узкое место дальше по конвейеру
+	GenBlockedTotal     time.Duration `json:"gen_blocked_total"`
+	CollectBlockedTotal time.Duration `json:"collect_blocked_total"`
+
+	Latency *Latency `json:"latency,omitempty"` // время обработки чисел воркерами; nil без WithLatencyHistogram
+}
+
+// StopReason — причина, по которой остановилась генерация.
+type StopReason int32
+
+const (
+	// StopNone — причина неизвестна: Result получен не от Run.
+	StopNone StopReason = iota
+	// StopDeadline — генерацию остановила отмена контекста: таймаут,
+	// дедлайн, сигнал или WithIdleTimeout.
+	StopDeadline
+	// StopCount — генератор исчерпал свою последовательность.
+	StopCount
+	// StopError — Run завершился ошибкой, например *ShutdownError.
+	StopError
+)
+
+// String возвращает название причины: none, deadline, count или error.
+func (s StopReason) String() string {
+	switch s {
+	case StopNone:
+		return "none"
+	case StopDeadline:
+		return "deadline"
+	case StopCount:
+		return "count"
+	case StopError:
+		return "error"
+	default:
+		return fmt.Sprintf("StopReason(%d)", int32(s))
+	}
+}
+
+// MarshalText выводит причину в JSON названием, а не числом.
+func (s StopReason) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText разбирает название причины, выведенное MarshalText.
+func (s *StopReason) UnmarshalText(text []byte) error {
+	for r := StopNone; r <= StopError; r++ {
+		if r.String() == string(text) {
+			*s = r
+			return nil
+		}
+	}
+	var n int32
+	if _, err := fmt.Sscanf(string(text), "StopReason(%d)", &n); err != nil {
+		return fmt.Errorf("неизвестная причина остановки %q", text)
+	}
+	*s = StopReason(n)
+	return nil
+}
+
+// JSON возвращает итоги в виде JSON с именами полей из тегов Result.
+// PerChannel всегда выводится массивом, в том числе пустым при nil, так что
+// ParseResult восстанавливает итоги поле в поле, а nil-разбивку — пустым
+// слайсом.
+func (r Result) JSON() ([]byte, error) {
+	if r.PerChannel == nil {
+		r.PerChannel = []int64{}
+	}
+	return json.Marshal(r)
+}
+
+// ParseResult разбирает итоги, выведенные Result.JSON. Неизвестные поля
+// пропускаются, чтобы итоги из более новых версий тоже читались.
+func ParseResult(data []byte) (Result, error) {
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Result{}, fmt.Errorf("разбор итогов: %w", err)
+	}
+	return r, nil
+}
+
+// Verify проверяет инварианты сохранения чисел: суммы и количества чисел на
+// входе равны суммам и количествам на выходе вместе с недоставленными и
+// отброшенными, а сумма разбивки по каналам равна количеству доставленных
+// чисел. Если инварианты нарушены, Verify возвращает ошибку с описанием
+// каждого нарушения.
+func (r Result) Verify() error {
+	var errs []error
+	if sum := r.OutputSum + r.DeadLetteredSum + r.DroppedSum; r.InputSum != sum {
+		errs = append(errs, fmt.Errorf("суммы чисел не равны: %d != %d", r.InputSum, sum))
+	}
+	if count := r.OutputCount + r.DeadLettered + r.Dropped; r.InputCount != count {
+		errs = append(errs, fmt.Errorf("количество чисел не равно: %d != %d", r.InputCount, count))
+	}
+	if err := assertConservation(r.InputCount-r.DeadLettered-r.Dropped, r.PerChannel); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ConservationError описывает неверную разбивку чисел по каналам: сумма
+// разбивки не равна количеству чисел, которые должны были пройти через
+// каналы, или в разбивке есть отрицательные счётчики.
+type ConservationError struct {
+	Expected    int64   // сколько чисел должно было пройти через каналы
+	Distributed int64   // сумма разбивки по каналам
+	PerChannel  []int64 // проверенная разбивка
+	Negative    []int   // индексы каналов с отрицательным счётчиком
+}
+
+func (e *ConservationError) Error() string {
+	msg := fmt.Sprintf("разделение чисел по каналам неверное: по каналам %d, доставлено %d", e.Distributed, e.Expected)
+	if len(e.Negative) > 0 {
+		msg += fmt.Sprintf(", отрицательные счётчики в каналах %v", e.Negative)
+	}
+	return msg
+}
+
+// assertConservation сверяет разбивку perChannel с количеством чисел
+// expected, не изменяя ни одного счётчика, и возвращает
+// *ConservationError, если сумма разбивки не совпадает или какой-то
+// счётчик отрицателен: отрицательный счётчик означает ошибку подсчёта, даже
+// если сумма случайно сошлась.
+func assertConservation(expected int64, perChannel []int64) error {
+	var distributed int64
+	var negative []int
+	for i, v := range perChannel {
+		distributed += v
+		if v < 0 {
+			negative = append(negative, i)
+		}
+	}
+	if distributed == expected && len(negative) == 0 {
+		return nil
+	}
+	return &ConservationError{
+		Expected:    expected,
+		Distributed: distributed,
+		PerChannel:  perChannel,
+		Negative:    negative,
+	}
+}
+
+// PerChannelPercent возвращает долю каждого канала воркера в процентах от
+// общего количества чисел в PerChannel. Если чисел не было, все доли равны
+// нулю.
+func (r Result) PerChannelPercent() []float64 {
+	percent := make([]float64, len(r.PerChannel))
+	var total int64
+	for _, v := range r.PerChannel {
+		total += v
+	}
+	if total == 0 {
+		return percent
+	}
+	for i, v := range r.PerChannel {
+		percent[i] = float64(v) / float64(total) * 100
+	}
+	return percent
+}
+
+// Labels — подписи строк текстовых итогов (см. Result.Text).
+type Labels struct {
+	Count      string
+	Sum        string
+	PerChannel string
+	Stopped    string
+}
+
+// Подписи текстовых итогов на поддерживаемых языках.
+var (
+	LabelsRU = Labels{Count: "Количество чисел", Sum: "Сумма чисел", PerChannel: "Разбивка по каналам", Stopped: "Завершено:"}
+	LabelsEN = Labels{Count: "Count", Sum: "Sum", PerChannel: "Per-channel", Stopped: "Stopped:"}
+)
+
+// String возвращает итоги в виде строк для человека — количество и сумма
+// чисел на входе и выходе, разбивка по каналам и, если она известна,
+// причина остановки ("Завершено: deadline") — без перевода строки в конце.
+// Подписи берутся из LabelsRU.
+func (r Result) String() string {
+	return r.Text(LabelsRU)
+}
+
+// Text — вариант String с подписями строк l; числа выводятся в том же
+// виде.
+func (r Result) Text(l Labels) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, l.Count, r.InputCount, r.OutputCount)
+	fmt.Fprintln(&b, l.Sum, r.InputSum, r.OutputSum)
+	fmt.Fprintf(&b, "%s %v", l.PerChannel, r.PerChannel)
+	if r.StopCause != "" {
+		fmt.Fprintf(&b, "\n%s %s", l.Stopped, r.StopCause)
+	}
+	return b.String()
+}
+
+// Fairness возвращает индекс справедливости Джейна для разбивки по каналам
+// PerChannel: (Σx)² / (n·Σx²). Значение лежит в (0, 1]: 1 означает
+// идеально равномерное распределение, 1/n — все числа в одном канале. Для
+// пустой разбивки или разбивки из одних нулей индекс не определён, и
+// Fairness возвращает 0.
+func (r Result) Fairness() float64 {
+	var sum, sumSquares float64
+	for _, v := range r.PerChannel {
+		x := float64(v)
+		sum += x
+		sumSquares += x * x
+	}
+	if sumSquares == 0 {
+		return 0
+	}
+	return sum * sum / (float64(len(r.PerChannel)) * sumSquares)
+}
+
+// ChiSquareUniform возвращает статистику хи-квадрат Пирсона для разбивки по
+// каналам PerChannel против равномерного распределения Σ(x−E)²/E, где
+// E — среднее по каналам, и число степеней свободы dof = n−1.
+//
+// Если воркеры получают числа случайно и равновероятно, statistic в среднем
+// близка к dof, а значения больше dof + 3·√(2·dof) почти наверняка
+// означают перекос, например из-за буферизации или планирования. Очерёдное
+// распределение даёт statistic около нуля: такая разбивка ровнее случайной.
+// Для пустой разбивки или разбивки из одних нулей statistic равна 0.
+func (r Result) ChiSquareUniform() (statistic float64, dof int) {
+	n := len(r.PerChannel)
+	if n == 0 {
+		return 0, 0
+	}
+	var total float64
+	for _, v := range r.PerChannel {
+		total += float64(v)
+	}
+	if total == 0 {
+		return 0, n - 1
+	}
+
+	expected := total / float64(n)
+	for _, v := range r.PerChannel {
+		d := float64(v) - expected
+		statistic += d * d / expected
+	}
+	return statistic, n - 1
+}
+
+// Equal сообщает, совпадают ли итоги r и other во всех полях, включая
+// поэлементно PerChannel и Latency. Пустая и nil-разбивка по каналам
+// считаются равными.
+func (r Result) Equal(other Result) bool {
+	return r.Diff(other) == ""
+}
+
+// Diff описывает различия между r и other по строке на каждое
+// несовпадающее поле в виде "InputCount: 10 != 12"; для PerChannel
+// различия выводятся поэлементно ("PerChannel[2]: 3 != 4"), а отсутствующие
+// элементы — как "<нет>". Если итоги совпадают, Diff возвращает пустую
+// строку.
+func (r Result) Diff(other Result) string {
+	var b strings.Builder
+	a, o := reflect.ValueOf(r), reflect.ValueOf(other)
+	for i := 0; i < a.NumField(); i++ {
+		name := a.Type().Field(i).Name
+		x, y := a.Field(i), o.Field(i)
+		if x.Kind() == reflect.Slice {
+			diffSlice(&b, name, x, y)
+			continue
+		}
+		if !reflect.DeepEqual(x.Interface(), y.Interface()) {
+			fmt.Fprintf(&b, "%s: %v != %v\n", name, diffValue(x), diffValue(y))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// diffSlice пишет в b поэлементные различия слайсов x и y поля name.
+func diffSlice(b *strings.Builder, name string, x, y reflect.Value) {
+	for i := 0; i < max(x.Len(), y.Len()); i++ {
+		var vx, vy any = "<нет>", "<нет>"
+		if i < x.Len() {
+			vx = x.Index(i).Interface()
+		}
+		if i < y.Len() {
+			vy = y.Index(i).Interface()
+		}
+		if vx != vy {
+			fmt.Fprintf(b, "%s[%d]: %v != %v\n", name, i, vx, vy)
+		}
+	}
+}
+
+// diffValue возвращает значение поля для Diff: строки — в кавычках, чтобы
+// была видна пустая строка, а указатели — разыменованными, чтобы вместо
+// адреса выводилось содержимое.
+func diffValue(v reflect.Value) any {
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%+v", v.Elem().Interface())
+	}
+	return v.Interface()
+}
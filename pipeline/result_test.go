@@ -0,0 +1,325 @@
+package pipeline
+
+import (
+	"errors"
+	"flag"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inconsistent — итоги, в которых нарушены все инварианты Verify.
+var inconsistent = Result{
+	InputCount:  10,
+	InputSum:    55,
+	OutputCount: 11,
+	OutputSum:   66,
+	PerChannel:  []int64{3, 3, 3},
+}
+
+func TestVerifyMessagesWellFormed(t *testing.T) {
+	err := inconsistent.Verify()
+	if err == nil {
+		t.Fatal("Verify не нашла расхождений")
+	}
+
+	// каждое нарушение — отдельная строка вида "...: A != B" или описание
+	// разбивки, без мусора от форматирования вроде "%!d" или "11n"
+	wellFormed := regexp.MustCompile(`^[^%\n]*[^%\sn]$`)
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("ожидалось 3 нарушения, получено %d: %q", len(lines), err)
+	}
+	for _, line := range lines {
+		if !wellFormed.MatchString(line) {
+			t.Errorf("сообщение сформировано неверно: %q", line)
+		}
+	}
+}
+
+func TestVerifyReportsEachInvariant(t *testing.T) {
+	consistent := Result{
+		InputCount:  10,
+		InputSum:    55,
+		OutputCount: 10,
+		OutputSum:   55,
+		PerChannel:  []int64{4, 3, 3},
+	}
+	if err := consistent.Verify(); err != nil {
+		t.Fatalf("согласованные итоги не прошли проверку: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Result)
+		want   string
+	}{
+		{"сумма", func(r *Result) { r.OutputSum = 50 }, "суммы чисел не равны: 55 != 50"},
+		{"количество", func(r *Result) { r.OutputCount = 9 }, "количество чисел не равно: 10 != 9"},
+		{"разбивка", func(r *Result) { r.PerChannel = []int64{3, 3, 3} },
+			"разделение чисел по каналам неверное: по каналам 9, доставлено 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := consistent
+			r.PerChannel = slices.Clone(consistent.PerChannel)
+			tt.modify(&r)
+			err := r.Verify()
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("получено %v, ожидалось %q", err, tt.want)
+			}
+		})
+	}
+
+	err := inconsistent.Verify()
+	var conservation *ConservationError
+	if !errors.As(err, &conservation) {
+		t.Fatalf("в ошибке %v нет *ConservationError", err)
+	}
+	for _, want := range []string{"суммы чисел не равны: 55 != 66", "количество чисел не равно: 10 != 11"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("в ошибке %q нет %q", err, want)
+		}
+	}
+}
+
+func TestPerChannelPercent(t *testing.T) {
+	r := Result{PerChannel: []int64{1, 2, 3, 4}}
+	percent := r.PerChannelPercent()
+	var total float64
+	for _, p := range percent {
+		total += p
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Fatalf("доли %v дают в сумме %v, ожидалось 100", percent, total)
+	}
+	if math.Abs(percent[3]-40) > 1e-9 {
+		t.Fatalf("доля четвёртого канала %v, ожидалось 40", percent[3])
+	}
+
+	zero := Result{PerChannel: []int64{0, 0, 0}}.PerChannelPercent()
+	if !slices.Equal(zero, []float64{0, 0, 0}) {
+		t.Fatalf("без чисел получены доли %v, ожидались нули", zero)
+	}
+}
+
+var update = flag.Bool("update", false, "перезаписать эталонные файлы в testdata")
+
+func TestResultStringGolden(t *testing.T) {
+	r := Result{
+		InputCount:  6,
+		InputSum:    21,
+		OutputCount: 6,
+		OutputSum:   21,
+		PerChannel:  []int64{2, 3, 1},
+		StopCause:   "count",
+	}
+	golden := filepath.Join("testdata", "result.golden")
+	got := r.String() + "\n"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Fatalf("String() = %q, эталон %q (перезаписать: go test -update)", got, want)
+	}
+}
+
+func TestResultTextLocales(t *testing.T) {
+	r := Result{InputCount: 3, InputSum: 6, OutputCount: 3, OutputSum: 6, PerChannel: []int64{1, 2}}
+	tests := []struct {
+		name   string
+		labels Labels
+		want   string
+	}{
+		{"ru", LabelsRU, "Количество чисел 3 3\nСумма чисел 6 6\nРазбивка по каналам [1 2]"},
+		{"en", LabelsEN, "Count 3 3\nSum 6 6\nPer-channel [1 2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Text(tt.labels); got != tt.want {
+				t.Fatalf("получено %q, ожидалось %q", got, tt.want)
+			}
+			stopped := r
+			stopped.StopCause = "signal"
+			want := tt.want + "\n" + tt.labels.Stopped + " signal"
+			if got := stopped.Text(tt.labels); got != want {
+				t.Fatalf("получено %q, ожидалось %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFairness(t *testing.T) {
+	tests := []struct {
+		name       string
+		perChannel []int64
+		want       float64
+	}{
+		{"равномерно", []int64{25, 25, 25, 25}, 1},
+		{"всё в одном канале", []int64{100, 0, 0, 0}, 0.25},
+		{"пусто", []int64{0, 0, 0}, 0},
+		{"без каналов", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Result{PerChannel: tt.perChannel}.Fairness()
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("Fairness(%v) = %v, ожидалось %v", tt.perChannel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChiSquareUniform(t *testing.T) {
+	tests := []struct {
+		name       string
+		perChannel []int64
+		stat       float64
+		dof        int
+	}{
+		{"равномерно", []int64{25, 25, 25, 25}, 0, 3},
+		// ожидается по 25: (100−25)²/25 + 3·25²/25 = 225 + 75
+		{"всё в одном канале", []int64{100, 0, 0, 0}, 300, 3},
+		{"пусто", []int64{0, 0, 0}, 0, 2},
+		{"без каналов", nil, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stat, dof := Result{PerChannel: tt.perChannel}.ChiSquareUniform()
+			if math.Abs(stat-tt.stat) > 1e-9 || dof != tt.dof {
+				t.Fatalf("ChiSquareUniform(%v) = %v, %d, ожидалось %v, %d",
+					tt.perChannel, stat, dof, tt.stat, tt.dof)
+			}
+		})
+	}
+
+	// перекос уверенно выходит за порог dof + 3·√(2·dof) из документации
+	stat, dof := Result{PerChannel: []int64{100, 0, 0, 0}}.ChiSquareUniform()
+	if limit := float64(dof) + 3*math.Sqrt(float64(2*dof)); stat <= limit {
+		t.Fatalf("статистика перекоса %v не больше порога %v", stat, limit)
+	}
+}
+
+func TestAssertConservation(t *testing.T) {
+	tests := []struct {
+		name        string
+		perChannel  []int64
+		distributed int64
+		negative    []int
+	}{
+		{"сходится", []int64{3, 3, 4}, 10, nil},
+		{"не хватает", []int64{3, 3, 3}, 9, nil},
+		{"сумма сошлась за счёт отрицательного", []int64{7, 5, -2}, 10, []int{2}},
+		{"отрицательный и не сходится", []int64{-1, 4}, 3, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perChannel := slices.Clone(tt.perChannel)
+			err := assertConservation(10, perChannel)
+			if !slices.Equal(perChannel, tt.perChannel) {
+				t.Fatalf("разбивка изменена: %v", perChannel)
+			}
+			if tt.distributed == 10 && tt.negative == nil {
+				if err != nil {
+					t.Fatalf("верная разбивка отклонена: %v", err)
+				}
+				return
+			}
+			var ce *ConservationError
+			if !errors.As(err, &ce) {
+				t.Fatalf("получено %v, ожидалась *ConservationError", err)
+			}
+			if ce.Expected != 10 || ce.Distributed != tt.distributed || !slices.Equal(ce.Negative, tt.negative) {
+				t.Fatalf("получено %+v, ожидалось по каналам %d, отрицательные %v", ce, tt.distributed, tt.negative)
+			}
+		})
+	}
+}
+
+// randomResult заполняет все поля Result случайными значениями из rng.
+func randomResult(rng *rand.Rand) Result {
+	r := Result{
+		InputCount:          rng.Int64(),
+		InputSum:            rng.Int64() - math.MaxInt64/2,
+		OutputCount:         rng.Int64(),
+		OutputSum:           -rng.Int64(),
+		DeadLettered:        rng.Int64N(1000),
+		DeadLetteredSum:     rng.Int64(),
+		Dropped:             rng.Int64N(1000),
+		DroppedSum:          rng.Int64(),
+		Min:                 -rng.Int64(),
+		Max:                 rng.Int64(),
+		Empty:               rng.IntN(2) == 0,
+		Overflow:            rng.IntN(2) == 0,
+		StopReason:          StopReason(rng.IntN(int(StopError) + 1)),
+		StopCause:           []string{"", "deadline", "signal", "count"}[rng.IntN(4)],
+		GenBlockedTotal:     time.Duration(rng.Int64()),
+		CollectBlockedTotal: time.Duration(rng.Int64()),
+	}
+	if n := rng.IntN(5); n > 0 {
+		r.PerChannel = make([]int64, n)
+		for i := range r.PerChannel {
+			r.PerChannel[i] = rng.Int64N(1 << 40)
+		}
+	}
+	if rng.IntN(2) == 0 {
+		r.Latency = &Latency{
+			Bounds: []time.Duration{time.Millisecond, time.Second},
+			Counts: []int64{rng.Int64N(100), rng.Int64N(100), rng.Int64N(100)},
+			Total:  time.Duration(rng.Int64()),
+			Count:  rng.Int64N(300),
+		}
+	}
+	return r
+}
+
+func TestResultJSONRoundTripRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 200; i++ {
+		r := randomResult(rng)
+		data, err := r.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseResult(data)
+		if err != nil {
+			t.Fatalf("разбор %s: %v", data, err)
+		}
+		if !parsed.Equal(r) {
+			t.Fatalf("итоги после разбора отличаются:\n%s\nJSON: %s", parsed.Diff(r), data)
+		}
+	}
+}
+
+func TestResultEqualAndDiff(t *testing.T) {
+	a := Result{InputCount: 10, OutputCount: 10, PerChannel: []int64{3, 3, 4}}
+	if !a.Equal(a) || a.Diff(a) != "" {
+		t.Fatalf("итоги не равны самим себе: %q", a.Diff(a))
+	}
+	if !(Result{}).Equal(Result{PerChannel: []int64{}}) {
+		t.Fatal("nil и пустая разбивка считаются разными")
+	}
+
+	b := a
+	b.OutputCount = 12
+	b.PerChannel = []int64{3, 3, 5, 1}
+	if a.Equal(b) {
+		t.Fatal("разные итоги признаны равными")
+	}
+	want := "OutputCount: 10 != 12\nPerChannel[2]: 4 != 5\nPerChannel[3]: <нет> != 1"
+	if got := a.Diff(b); got != want {
+		t.Fatalf("Diff = %q, ожидалось %q", got, want)
+	}
+}
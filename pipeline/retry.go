@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// WithRetry включает преобразование чисел в воркерах так же, как
+// WithTransform, но при ошибке transform вызов повторяется, пока не будет
+// сделано attempts попыток; между попытками выжидается backoff по часам
+// пакета (см. SetClock). Только ошибка последней попытки отправляет число в
+// DeadLetter. Ожидание между попытками прерывает отмена контекста воркера,
+// то есть аварийная остановка Run, а не конец генерации. При attempts < 1
+// делается одна попытка. WithRetry заменяет transform, заданный
+// WithTransform, и наоборот.
+func WithRetry(transform func(int64) (int64, error), attempts int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.transform = transform
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// retry оборачивает transform повторами для WithRetry: обёртка возвращает
+// ошибку последней попытки или, если ctx отменён во время ожидания между
+// попытками, ctx.Err().
+func retry(ctx context.Context, transform func(int64) (int64, error), attempts int, backoff time.Duration) func(int64) (int64, error) {
+	attempts = max(attempts, 1)
+
+	return func(v int64) (int64, error) {
+		var err error
+		for attempt := 1; ; attempt++ {
+			var res int64
+			res, err = transform(v)
+			if err == nil || attempt == attempts {
+				return res, err
+			}
+
+			if backoff <= 0 {
+				if ctx.Err() != nil {
+					return 0, ctx.Err()
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-clk().After(backoff):
+			}
+		}
+	}
+}
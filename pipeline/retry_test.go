@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("временный сбой")
+
+// failing возвращает transform, который первые failures вызовов
+// возвращает errFlaky, а затем удваивает число, и счётчик его вызовов.
+func failing(failures int) (transform func(int64) (int64, error), calls *int) {
+	calls = new(int)
+	return func(v int64) (int64, error) {
+		*calls++
+		if *calls <= failures {
+			return 0, errFlaky
+		}
+		return v * 2, nil
+	}, calls
+}
+
+func TestRetryWrapperSucceedsOnThirdAttempt(t *testing.T) {
+	transform, calls := failing(2)
+	res, err := retry(context.Background(), transform, 3, 0)(21)
+	if err != nil || res != 42 {
+		t.Fatalf("получено (%d, %v), ожидалось (42, nil)", res, err)
+	}
+	if *calls != 3 {
+		t.Fatalf("transform вызван %d раз, ожидалось 3", *calls)
+	}
+}
+
+func TestRetryWrapperGivesUpAfterAttempts(t *testing.T) {
+	transform, calls := failing(1 << 30)
+	_, err := retry(context.Background(), transform, 3, 0)(1)
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("получено %v, ожидалась ошибка последней попытки", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("transform вызван %d раз, ожидалось 3", *calls)
+	}
+}
+
+func TestRetryWrapperWaitsBackoffOnPackageClock(t *testing.T) {
+	c := useFakeClock(t)
+	transform, calls := failing(2)
+	done := make(chan error, 1)
+	go func() {
+		_, err := retry(context.Background(), transform, 3, time.Second)(1)
+		done <- err
+	}()
+	for i := 0; i < 2; i++ {
+		waitWaiters(t, c, 1)
+		c.Advance(time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("получено %v после двух сбоев и трёх попыток", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("transform вызван %d раз, ожидалось 3", *calls)
+	}
+}
+
+func TestRetryWrapperCancelStopsBackoff(t *testing.T) {
+	useFakeClock(t) // часы не сдвигаются: ожидание может прервать только отмена
+	ctx, cancel := context.WithCancel(context.Background())
+	transform, _ := failing(1 << 30)
+	done := make(chan error, 1)
+	go func() {
+		_, err := retry(ctx, transform, 5, time.Hour)(1)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("получено %v, ожидалась context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("отмена не прервала ожидание между попытками")
+	}
+}
+
+func TestRunWithRetryForwardsAfterTwoFailures(t *testing.T) {
+	// один воркер: первое число дважды падает и проходит с третьей попытки,
+	// остальные проходят сразу
+	transform, calls := failing(2)
+	result, err := RunBounded(context.Background(), 1, 5, WithDelay(0), WithRetry(transform, 3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OutputCount != 5 || result.DeadLettered != 0 {
+		t.Fatalf("доставлено %d, недоставлено %d; ожидалось 5 и 0", result.OutputCount, result.DeadLettered)
+	}
+	if result.OutputSum != 2*(1+2+3+4+5) {
+		t.Fatalf("сумма на выходе %d, ожидалось %d", result.OutputSum, 2*(1+2+3+4+5))
+	}
+	if *calls != 7 {
+		t.Fatalf("transform вызван %d раз, ожидалось 7", *calls)
+	}
+}
+
+func TestRunWithRetryDeadLettersAfterAttempts(t *testing.T) {
+	transform, calls := failing(1 << 30)
+	result, err := RunBounded(context.Background(), 1, 5, WithDelay(0), WithRetry(transform, 3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OutputCount != 0 || result.DeadLettered != 5 {
+		t.Fatalf("доставлено %d, недоставлено %d; ожидалось 0 и 5", result.OutputCount, result.DeadLettered)
+	}
+	if *calls != 15 {
+		t.Fatalf("transform вызван %d раз, ожидалось 15", *calls)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,431 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Run запускает конвейер из генератора, numOut воркеров и сборщика и
+// дожидается, пока через него пройдут все числа. Генерация продолжается до
+// отмены ctx. Дополнительные параметры задаются через opts. Run возвращает
+// ошибку, если numOut меньше 1, а также *ShutdownError, если конвейер не
+// дочитался после отмены ctx за время WithShutdownTimeout; в последнем
+// случае в Result заполнены только итоги входа и разбивка по каналам.
+// Ошибка приёмника WithSink и ErrDoubleClose, если канал конвейера закрыли
+// дважды (например, генератор RunWith закрыл свой канал не один раз),
+// возвращаются вместе с полными итогами.
+// Причину остановки генерации Run берёт из context.Cause контекста
+// генератора и записывает в Result.StopCause, поэтому ctx стоит отменять с
+// причиной (ErrDeadline, ErrSignal); переполнение int64 останавливает
+// генерацию с причиной ErrOverflow.
+//
+// Воркеры и сборщик работают в режиме дренажа: отмена ctx останавливает
+// только генератор, а все числа, уже отправленные в конвейер, дочитываются
+// и пересылаются до конца. Функция подсчёта входа вызывается генератором до
+// закрытия входного канала, воркеры завершаются только после его закрытия,
+// а результирующий канал закрывается после завершения всех воркеров, поэтому
+// к моменту возврата Run входные и выходные итоги уже окончательны.
+//
+// Run создаёт спан OpenTelemetry pipeline.Run и дочерние спаны для
+// генератора, каждого воркера (атрибут worker.index) и сборщика (атрибут
+// processed.count) через глобальный провайдер трассировки.
+func Run(ctx context.Context, numOut int, opts ...Option) (Result, error) {
+	return RunWith(ctx, numOut, func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorFrom(ctx, ch, 1, fn, func(err error) { stopGeneration(ctx, err) })
+	}, opts...)
+}
+
+// GeneratorFunc — генератор для RunWith: отправляет числа в ch, вызывая fn
+// для каждого отправленного числа, и закрывает ch, когда числа кончаются или
+// отменяется ctx. Под эту сигнатуру подходит Generator; остальные
+// генераторы пакета подключаются через замыкания.
+type GeneratorFunc func(ctx context.Context, ch chan<- int64, fn func(int64))
+
+// RunWith работает как Run, но числа для конвейера отправляет gen вместо
+// Generator. Инварианты Result сохраняются для любого генератора, если он
+// вызывает fn для каждого отправленного числа и закрывает канал: Run
+// дожидается закрытия входного канала, поэтому конечный генератор
+// (например, GeneratorN) завершает конвейер и без отмены ctx.
+func RunWith(ctx context.Context, numOut int, gen GeneratorFunc, opts ...Option) (Result, error) {
+	if numOut < 1 {
+		return Result{}, fmt.Errorf("количество воркеров должно быть не меньше 1, получено %d", numOut)
+	}
+	cfg := newConfig(opts)
+
+	ctx, span := tracer().Start(ctx, spanRun)
+	defer span.End()
+	if id, ok := RunIDFrom(ctx); ok {
+		span.SetAttributes(attribute.String("run.id", id))
+	}
+
+	// closes следит, чтобы каждый канал конвейера закрывался один раз
+	ctx, closes := withCloseGuard(ctx)
+
+	// drainCtx сохраняет значения ctx, но не отменяется вместе с ним
+	drainCtx := context.WithoutCancel(ctx)
+
+	chIn := make(chan int64, cfg.inputBuffer)
+
+	// для проверки будем считать количество и сумму отправленных чисел
+	var inputSum int64   // сумма сгенерированных чисел
+	var inputCount int64 // количество сгенерированных чисел
+	var inputOverflow atomic.Bool
+
+	// время, которое генератор и сборщик ждали получателя при отправке, в
+	// наносекундах
+	var genBlocked, collectBlocked int64
+
+	// genCtx дополнительно отменяется, если генератор простаивает дольше
+	// cfg.idleTimeout или сам завершает генерацию через stopGeneration;
+	// причину отмены потом сообщает context.Cause
+	genCtx := ctx
+	if cfg.idleTimeout > 0 {
+		var stopIdle func()
+		genCtx, stopIdle = watchIdle(ctx, cfg.idleTimeout, &inputCount)
+		defer stopIdle()
+	}
+	genCtx, cancelGen := context.WithCancelCause(genCtx)
+	defer cancelGen(nil)
+	genCtx = context.WithValue(genCtx, stopGenerationKey{}, cancelGen)
+
+	// genDone и workersLeft позволяют понять, какая стадия зависла, если
+	// конвейер не дочитался за cfg.shutdownTimeout
+	genDone := make(chan struct{})
+	workersLeft := int64(numOut)
+	var stopReason atomic.Int32
+	var stopCause atomic.Value // string
+
+	// генерируем числа, считая параллельно их количество и сумму
+	go func() {
+		defer close(genDone)
+		ctx, span := tracer().Start(genCtx, spanGenerator)
+		defer span.End()
+		ctx = withBlocked(ctx, &genBlocked)
+
+		gen(ctx, chIn, func(i int64) {
+			// атомарное сложение даёт согласованную пару старого и нового
+			// значения даже при нескольких горутинах генератора
+			if sum := atomic.AddInt64(&inputSum, i); addOverflows(sum-i, i) {
+				inputOverflow.Store(true)
+			}
+			atomic.AddInt64(&inputCount, 1)
+			for _, o := range cfg.observers {
+				safeGenerated(ctx, o, i)
+			}
+		})
+
+		// генератор, завершившийся до отмены, исчерпал свою
+		// последовательность
+		cause := context.Cause(genCtx)
+		stopCause.Store(causeName(cause))
+		if cause != nil && !errors.Is(cause, ErrOverflow) {
+			stopReason.Store(int32(StopDeadline))
+		} else {
+			stopReason.Store(int32(StopCount))
+		}
+	}()
+
+	// amounts — слайс, в который собирается статистика по горутинам
+	amounts := make([]int64, numOut)
+
+	// недоставленные числа; горутина dl читает их до dl.Close, то есть до
+	// завершения всех воркеров
+	var dl *DeadLetter
+	if cfg.transform != nil {
+		dl = NewDeadLetter(nil)
+	}
+
+	var latency *LatencyHistogram
+	var hist func(time.Duration)
+	if cfg.latencyBuckets != nil {
+		latency = NewLatencyHistogram(cfg.latencyBuckets)
+		hist = latency.Record
+	}
+
+	// outs — каналы воркеров, дочитывающих chIn до закрытия; при разгоне
+	// воркер ждёт своей очереди, пока генератор не завершится по любой
+	// причине: отмена ctx, исчерпанная последовательность или
+	// stopGeneration
+	rampStop := genDone
+	work := func(ctx context.Context, index int, in <-chan int64, out chan<- int64) {
+		defer atomic.AddInt64(&workersLeft, -1)
+		if cfg.ramp > 0 && index > 0 {
+			select {
+			case <-clk().After(cfg.ramp * time.Duration(index) / time.Duration(numOut)):
+			case <-rampStop:
+			}
+		}
+		ctx, span := tracer().Start(ctx, spanWorker, trace.WithAttributes(attribute.Int("worker.index", index)))
+		defer span.End()
+		if cfg.lockThreads {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+		}
+
+		if dl != nil {
+			transform := cfg.transform
+			if cfg.retryAttempts > 1 {
+				transform = retry(ctx, transform, cfg.retryAttempts, cfg.retryBackoff)
+			}
+			transformForward(ctx, in, out, cfg.delay, dl, transform, hist)
+			return
+		}
+		cfg.forward(ctx, in, out, cfg.delay, hist)
+	}
+	// периодический отчёт о ходе работы читает счётчики атомарно, параллельно
+	// с генератором и сборщиком
+	stopProgress := func() {}
+	if cfg.progress != nil && cfg.progressInterval > 0 {
+		stopProgress = startProgress(ctx, cfg.progressInterval, func() Result {
+			return Result{
+				InputCount: atomic.LoadInt64(&inputCount),
+				InputSum:   atomic.LoadInt64(&inputSum),
+				PerChannel: loadAll(amounts),
+			}
+		}, cfg.progress)
+	}
+
+	var outs []<-chan int64
+	var queues []*WorkerQueue
+	switch {
+	case cfg.queueDepth > 0:
+		queues = make([]*WorkerQueue, numOut)
+		for i := range queues {
+			queues[i] = NewWorkerQueue(cfg.queueDepth, cfg.queuePolicy)
+		}
+		var d Distributor = NewRoundRobin(numOut)
+		if cfg.distributor != nil {
+			d = cfg.distributor(amounts)
+		}
+		outs = dispatchQueues(drainCtx, chIn, queues, d.Next, work)
+	case cfg.distributor != nil:
+		outs = dispatch(drainCtx, chIn, numOut, cfg.distributor(amounts).Next, work)
+	default:
+		outs = fanOut(drainCtx, chIn, numOut, work)
+	}
+
+	// chOut — канал, в который будут отправляться числа из горутин outs[i];
+	// паника наблюдателя перехватывается для каждого отдельно, чтобы
+	// следующие наблюдатели не пропустили число
+	collectCtx, collectSpan := tracer().Start(drainCtx, spanCollector)
+	var processed func(worker int, v int64)
+	if len(cfg.observers) > 0 {
+		processed = func(worker int, v int64) {
+			for _, o := range cfg.observers {
+				safeProcessed(collectCtx, o.Processed, worker, v)
+			}
+		}
+	}
+	outBuf := cfg.outputBuffer
+	if outBuf < 0 {
+		outBuf = numOut
+	}
+	chOut := collect(withBlocked(collectCtx, &collectBlocked), amounts, outs, outBuf, processed)
+
+	// читаем числа из результирующего канала; если после отмены ctx это
+	// затягивается дольше cfg.shutdownTimeout, возвращаем ошибку, оставляя
+	// зависшие горутины работать
+	statsCh := make(chan Stats, 1)
+	var sinkErr error // записывается до отправки в statsCh
+	go func() {
+		stats, err := drainToSink(chOut, cfg.sink, cancelGen)
+		sinkErr = err
+		statsCh <- stats
+	}()
+	stats, err := awaitShutdown(ctx, statsCh, cfg.shutdownTimeout, func() string {
+		select {
+		case <-genDone:
+		default:
+			return StageGenerator
+		}
+		if atomic.LoadInt64(&workersLeft) > 0 {
+			return StageWorker
+		}
+		return StageFanIn
+	})
+	if err != nil {
+		collectSpan.End()
+		stopProgress()
+		logFor(ctx).ErrorContext(ctx, "конвейер не завершился после отмены", "error", err)
+		return Result{
+			InputCount: atomic.LoadInt64(&inputCount),
+			InputSum:   atomic.LoadInt64(&inputSum),
+			PerChannel: loadAll(amounts),
+			StopReason: StopError,
+		}, err
+	}
+	collectSpan.SetAttributes(attribute.Int64("processed.count", stats.Count))
+	collectSpan.End()
+	stopProgress()
+
+	// входной канал закрыт, но генератор мог ещё не вернуться — например,
+	// если его закрыли дважды; ждём, чтобы причина остановки и ошибки
+	// закрытия были окончательными
+	<-genDone
+
+	// chOut закрыт, значит, все воркеры завершились и больше ничего не
+	// отправят в dl
+	var deadLettered, deadLetteredSum int64
+	if dl != nil {
+		dl.Close()
+		deadLettered, deadLetteredSum = dl.Count(), dl.Sum()
+	}
+
+	// очереди закрыты диспетчером до завершения воркеров, так что счётчики
+	// отброшенных чисел тоже окончательны
+	var dropped, droppedSum int64
+	for _, q := range queues {
+		dropped += q.Dropped()
+		droppedSum += q.DroppedSum()
+	}
+
+	// chOut закрыт только после wg.Wait() внутри collect, так что счётчики уже
+	// не меняются; но читаем их атомарно, как и пишем, чтобы отчёт оставался
+	// корректным под -race, даже если его начнут строить параллельно с работой
+	perChannel := loadAll(amounts)
+
+	result := Result{
+		InputCount:  atomic.LoadInt64(&inputCount),
+		InputSum:    atomic.LoadInt64(&inputSum),
+		OutputCount: stats.Count,
+		OutputSum:   stats.Sum,
+		PerChannel:  perChannel,
+
+		Min:      stats.Min,
+		Max:      stats.Max,
+		Empty:    stats.Empty,
+		Overflow: inputOverflow.Load() || stats.Overflow,
+
+		DeadLettered:    deadLettered,
+		DeadLetteredSum: deadLetteredSum,
+
+		Dropped:    dropped,
+		DroppedSum: droppedSum,
+
+		StopReason: StopReason(stopReason.Load()),
+		StopCause:  stopCause.Load().(string),
+
+		GenBlockedTotal:     time.Duration(atomic.LoadInt64(&genBlocked)),
+		CollectBlockedTotal: time.Duration(atomic.LoadInt64(&collectBlocked)),
+	}
+	if latency != nil {
+		l := latency.Snapshot()
+		result.Latency = &l
+	}
+	err = errors.Join(sinkErr, closes.Err())
+	if err != nil {
+		result.StopReason = StopError
+	}
+
+	logFor(ctx).InfoContext(ctx, "конвейер завершён",
+		"input_count", result.InputCount,
+		"input_sum", result.InputSum,
+		"output_count", result.OutputCount,
+		"output_sum", result.OutputSum,
+		"per_channel", result.PerChannel,
+		"dead_lettered", result.DeadLettered,
+		"dropped", result.Dropped,
+	)
+	if logConfigured() {
+		logSummary(ctx, logFor(ctx), result)
+	}
+	if err := result.Verify(); err != nil {
+		logFor(ctx).WarnContext(ctx, "итоги на входе и выходе конвейера не совпадают", "error", err)
+	}
+	if sinkErr != nil {
+		logFor(ctx).ErrorContext(ctx, "приёмник не принял число", "error", sinkErr)
+	}
+
+	return result, err
+}
+
+// RunBounded запускает конвейер так же, как Run, но генератор
+// останавливается сам, отправив maxCount чисел (см. GeneratorN), если ctx
+// не отменится раньше. Что из двух случилось, сообщает Result.StopReason:
+// StopCount, если отправлены все maxCount чисел, даже когда ctx отменился
+// в тот же момент, иначе StopDeadline; при ошибке Run — StopError.
+// Result.StopCause в первом случае равен count.
+// Инварианты Result сохраняются в любом случае.
+func RunBounded(ctx context.Context, numOut int, maxCount int64, opts ...Option) (Result, error) {
+	result, err := RunWith(ctx, numOut, func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorN(ctx, ch, maxCount, fn)
+	}, opts...)
+	if err == nil && result.InputCount == max(maxCount, 0) {
+		result.StopReason = StopCount
+		result.StopCause = causeName(nil)
+	}
+	return result, err
+}
+
+// loadAll возвращает копию счётчиков amounts, прочитанных атомарно.
+func loadAll(amounts []int64) []int64 {
+	values := make([]int64, len(amounts))
+	for i := range amounts {
+		values[i] = atomic.LoadInt64(&amounts[i])
+	}
+	return values
+}
+
+// startProgress каждые interval передаёт в report результат snapshot, пока
+// не отменится ctx или не будет вызвана возвращённая функция остановки.
+// Функция остановки дожидается завершения горутины отчёта, поэтому после её
+// возврата report больше не вызывается.
+func startProgress(ctx context.Context, interval time.Duration, snapshot func() Result, report func(Result)) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-clk().After(interval):
+			}
+			report(snapshot())
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// watchIdle возвращает контекст, производный от ctx, который отменяется,
+// если счётчик count не увеличился за целое окно idle. Проверка выполняется
+// раз в окно, поэтому простой обнаруживается через время от idle до 2*idle
+// после последнего числа, но пока числа идут, отмены не бывает. Функция
+// stop останавливает наблюдение и освобождает контекст. Причина отмены по
+// простою — ErrIdleTimeout.
+func watchIdle(ctx context.Context, idle time.Duration, count *int64) (idleCtx context.Context, stop func()) {
+	idleCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		prev := atomic.LoadInt64(count)
+		for {
+			select {
+			case <-idleCtx.Done():
+				return
+			case <-clk().After(idle):
+			}
+
+			cur := atomic.LoadInt64(count)
+			if cur == prev {
+				logFor(ctx).InfoContext(ctx, "генератор простаивает, генерация остановлена", "idle_timeout", idle)
+				cancel(ErrIdleTimeout)
+				return
+			}
+			prev = cur
+		}
+	}()
+	return idleCtx, func() { cancel(nil) }
+}
@@ -0,0 +1,340 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRampEarlyWorkersCountMore(t *testing.T) {
+	result, err := RunBounded(context.Background(), 5, 1500, WithRamp(500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(result.PerChannel); i++ {
+		if result.PerChannel[i-1] <= result.PerChannel[i] {
+			t.Fatalf("воркер %d запущен раньше, но обработал не больше воркера %d: %v",
+				i-1, i, result.PerChannel)
+		}
+	}
+}
+
+func TestRampStartsRemainingWorkersWhenGenerationEnds(t *testing.T) {
+	start := time.Now()
+	result, err := RunBounded(context.Background(), 5, 10, WithRamp(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("конвейер из 10 чисел завершился за %v: воркеры ждали разгона после генерации", elapsed)
+	}
+	if result.InputCount != 10 {
+		t.Fatalf("отправлено %d чисел, ожидалось 10", result.InputCount)
+	}
+}
+
+// captureLog направляет журнал пакета в буфер до конца теста. Обработчик
+// slog сам упорядочивает записи, так что буфер можно не защищать; читать
+// его следует после возврата Run.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { SetLogger(nil) })
+	return &buf
+}
+
+// panicObserver паникует на каждом уведомлении.
+type panicObserver struct{}
+
+func (panicObserver) Generated(int64)      { panic("generated") }
+func (panicObserver) Processed(int, int64) { panic("processed") }
+
+func TestRunSurvivesPanics(t *testing.T) {
+	logs := captureLog(t)
+	transform := func(v int64) (int64, error) {
+		if v%7 == 0 {
+			panic(errors.New("кратно 7"))
+		}
+		return v, nil
+	}
+
+	result, err := RunBounded(context.Background(), 4, 700,
+		WithDelay(0), WithTransform(transform), WithObserver(panicObserver{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if result.InputCount != 700 || result.DeadLettered != 100 || result.OutputCount != 600 {
+		t.Fatalf("вход %d, недоставлено %d, выход %d; ожидалось 700, 100, 600",
+			result.InputCount, result.DeadLettered, result.OutputCount)
+	}
+	if !strings.Contains(logs.String(), "паника в преобразовании числа") ||
+		!strings.Contains(logs.String(), "паника в наблюдателе") {
+		t.Errorf("паники не попали в журнал:\n%s", logs)
+	}
+}
+
+// countingObserver считает уведомления о пересланных числах.
+type countingObserver struct{ processed atomic.Int64 }
+
+func (o *countingObserver) Generated(int64)      {}
+func (o *countingObserver) Processed(int, int64) { o.processed.Add(1) }
+
+// Паника одного наблюдателя не должна лишать уведомлений следующих.
+func TestRunObserverAfterPanickingOne(t *testing.T) {
+	captureLog(t)
+	counter := &countingObserver{}
+	result, err := RunBounded(context.Background(), 4, 500,
+		WithDelay(0), WithObserver(panicObserver{}), WithObserver(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := counter.processed.Load(); got != result.OutputCount {
+		t.Fatalf("наблюдатель получил %d уведомлений, ожидалось %d", got, result.OutputCount)
+	}
+}
+
+// После отмены каждое число, принятое воркером, должно дойти до сборщика:
+// при любом моменте отмены итоги сходятся.
+func TestRunDrainOnCancelStress(t *testing.T) {
+	iterations := 1000
+	if testing.Short() {
+		iterations = 100
+	}
+	for i := 0; i < iterations; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%50)*10*time.Microsecond)
+		result, err := Run(ctx, 4, WithDelay(0))
+		cancel()
+		if err != nil {
+			t.Fatalf("итерация %d: %v", i, err)
+		}
+		if err := result.Verify(); err != nil {
+			t.Fatalf("итерация %d: итоги не сходятся: %v", i, err)
+		}
+	}
+}
+
+// Промежуточные отчёты читают PerChannel, пока воркеры его увеличивают;
+// под -race это проверяет, что оба доступа атомарны.
+func TestRunPerChannelReadConcurrently(t *testing.T) {
+	var last []int64
+	result, err := RunBounded(context.Background(), 4, 20000,
+		WithDelay(0),
+		WithProgress(time.Millisecond, func(r Result) {
+			for i := range r.PerChannel {
+				if last != nil && r.PerChannel[i] < last[i] {
+					t.Errorf("счётчик воркера %d уменьшился: %d < %d", i, r.PerChannel[i], last[i])
+				}
+			}
+			last = r.PerChannel
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range last {
+		if result.PerChannel[i] < last[i] {
+			t.Fatalf("итог воркера %d меньше промежуточного: %d < %d", i, result.PerChannel[i], last[i])
+		}
+	}
+}
+
+func TestRunCollectorFuncCountsPerWorker(t *testing.T) {
+	const workers = 4
+	var calls [workers]atomic.Int64
+	var sum atomic.Int64
+	result, err := RunBounded(context.Background(), workers, 2000,
+		WithDelay(0),
+		WithCollectorFunc(func(workerID int, v int64) {
+			calls[workerID].Add(1)
+			sum.Add(v)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range calls {
+		if got := calls[i].Load(); got != result.PerChannel[i] {
+			t.Fatalf("для воркера %d вызовов %d, ожидалось %d", i, got, result.PerChannel[i])
+		}
+	}
+	if sum.Load() != result.OutputSum {
+		t.Fatalf("сумма переданных чисел %d, ожидалось %d", sum.Load(), result.OutputSum)
+	}
+}
+
+func TestRunDeadLettersMultiplesOfThree(t *testing.T) {
+	rejectThrees := func(v int64) (int64, error) {
+		if v%3 == 0 {
+			return 0, errors.New("кратно трём")
+		}
+		return v, nil
+	}
+	result, err := RunBounded(context.Background(), 4, 300, WithDelay(0), WithTransform(rejectThrees))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DeadLettered != 100 {
+		t.Fatalf("недоставлено %d чисел, ожидалось 100", result.DeadLettered)
+	}
+	// 3+6+...+300 = 3*(1+...+100)
+	if result.DeadLetteredSum != 3*5050 {
+		t.Fatalf("сумма недоставленных %d, ожидалось %d", result.DeadLetteredSum, 3*5050)
+	}
+	if result.OutputCount != 200 {
+		t.Fatalf("доставлено %d чисел, ожидалось 200", result.OutputCount)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunWithGeneratorNExactCounts(t *testing.T) {
+	const n = 1234
+	gen := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		GeneratorN(ctx, ch, n, fn)
+	}
+	result, err := RunWith(context.Background(), 3, gen, WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InputCount != n || result.OutputCount != n {
+		t.Fatalf("отправлено %d, доставлено %d, ожидалось по %d", result.InputCount, result.OutputCount, n)
+	}
+	if want := int64(n * (n + 1) / 2); result.InputSum != want || result.OutputSum != want {
+		t.Fatalf("суммы %d и %d, ожидалось %d", result.InputSum, result.OutputSum, want)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdleTimeoutStopsPausedGenerator(t *testing.T) {
+	// генератор отправляет пять чисел и замолкает до отмены
+	gen := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		defer close(ch)
+		for v := int64(1); v <= 5; v++ {
+			if !sendCtx(ctx, ch, v) {
+				return
+			}
+			fn(v)
+		}
+		<-ctx.Done()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	result, err := RunWith(ctx, 2, gen, WithDelay(0), WithIdleTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("простой обнаружен через %v", elapsed)
+	}
+	if result.StopCause != "idle" {
+		t.Fatalf("StopCause = %q, ожидалось idle", result.StopCause)
+	}
+	if result.InputCount != 5 || result.OutputCount != 5 {
+		t.Fatalf("отправлено %d, доставлено %d, ожидалось по 5", result.InputCount, result.OutputCount)
+	}
+}
+
+// Идентификатор запуска доходит до воркеров: запись о панике в
+// преобразовании, которую делает воркер, помечена run_id.
+func TestRunIDReachesWorkers(t *testing.T) {
+	logs := captureLog(t)
+	transform := func(v int64) (int64, error) {
+		if v == 3 {
+			panic("три")
+		}
+		return v, nil
+	}
+	ctx := WithRunID(context.Background(), "run-42")
+	if _, err := RunBounded(ctx, 2, 10, WithDelay(0), WithTransform(transform)); err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "паника в преобразовании числа") {
+			found = true
+			if !strings.Contains(line, "run_id=run-42") {
+				t.Fatalf("в записи воркера нет run_id: %s", line)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("запись воркера о панике не найдена:\n%s", logs)
+	}
+}
+
+func TestGenBlockedWithSlowWorkers(t *testing.T) {
+	// два воркера с паузой 1ms не успевают за генератором, так что он
+	// большую часть времени ждёт на отправке
+	result, err := RunBounded(context.Background(), 2, 50, WithDelay(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.GenBlockedTotal < 10*time.Millisecond {
+		t.Fatalf("генератор ждал %v, ожидалось не меньше 10ms", result.GenBlockedTotal)
+	}
+}
+
+// failingSink отклоняет каждое число.
+type failingSink struct{}
+
+func (failingSink) Write(int64) error { return errors.New("приёмник недоступен") }
+func (failingSink) Close() error      { return nil }
+
+func TestRunStopReasons(t *testing.T) {
+	expired := func() context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		t.Cleanup(cancel)
+		return ctx
+	}
+	tests := []struct {
+		name string
+		run  func() (Result, error)
+		want StopReason
+	}{
+		{"deadline", func() (Result, error) { return Run(expired(), 2, WithDelay(0)) }, StopDeadline},
+		{"count", func() (Result, error) { return RunBounded(context.Background(), 2, 10, WithDelay(0)) }, StopCount},
+		{"error", func() (Result, error) {
+			return RunBounded(context.Background(), 2, 10, WithDelay(0), WithSink(failingSink{}))
+		}, StopError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.run()
+			if (err != nil) != (tt.want == StopError) {
+				t.Fatalf("ошибка %v при причине %v", err, tt.want)
+			}
+			if result.StopReason != tt.want {
+				t.Fatalf("StopReason = %v, ожидалось %v", result.StopReason, tt.want)
+			}
+		})
+	}
+
+	if (Result{}).StopReason != StopNone {
+		t.Fatal("у нулевого Result причина не StopNone")
+	}
+	for r := StopNone; r <= StopError; r++ {
+		var parsed StopReason
+		if err := parsed.UnmarshalText([]byte(r.String())); err != nil || parsed != r {
+			t.Fatalf("%v разобрана как %v, ошибка %v", r, parsed, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package pipeline
+
+import "context"
+
+// runIDKey — ключ идентификатора запуска в контексте.
+type runIDKey struct{}
+
+// WithRunID возвращает копию ctx с идентификатором запуска id. Run и
+// RunWith добавляют его к своим записям журнала (атрибут run_id) и
+// спанам (атрибут run.id), а воркеры, сборщик и наблюдатели, получающие
+// контекст конвейера, могут прочитать его через RunIDFrom: отмена ctx
+// останавливает только генератор, но значения контекста доходят до всех
+// стадий.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFrom возвращает идентификатор запуска из ctx и true, если он был
+// задан через WithRunID.
+func RunIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(runIDKey{}).(string)
+	return id, ok
+}
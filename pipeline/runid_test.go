@@ -0,0 +1,17 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunIDFrom(t *testing.T) {
+	if _, ok := RunIDFrom(context.Background()); ok {
+		t.Fatal("в пустом контексте найден идентификатор запуска")
+	}
+	ctx, cancel := context.WithCancel(WithRunID(context.Background(), "abc"))
+	defer cancel()
+	if id, ok := RunIDFrom(ctx); !ok || id != "abc" {
+		t.Fatalf("получено (%q, %v), ожидалось (abc, true)", id, ok)
+	}
+}
@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Стадии конвейера, которые называет ShutdownError.
+const (
+	StageGenerator = "generator" // генератор не закрыл входной канал
+	StageWorker    = "worker"    // не все воркеры завершились
+	StageFanIn     = "fan-in"    // сборщик не закрыл результирующий канал
+)
+
+// ShutdownError сообщает, что после отмены контекста конвейер не
+// дочитался за время, заданное WithShutdownTimeout.
+type ShutdownError struct {
+	Stage   string        // первая незавершившаяся стадия: StageGenerator, StageWorker или StageFanIn
+	Timeout time.Duration // отведённое на завершение время
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("конвейер не завершился за %v после отмены: зависла стадия %s", e.Timeout, e.Stage)
+}
+
+// awaitShutdown ждёт значения из done. Если timeout > 0 и после отмены ctx
+// значение не пришло за timeout, awaitShutdown возвращает *ShutdownError со
+// стадией, которую вернула stage.
+func awaitShutdown[T any](ctx context.Context, done <-chan T, timeout time.Duration, stage func() string) (T, error) {
+	if timeout <= 0 {
+		return <-done, nil
+	}
+
+	select {
+	case v := <-done:
+		return v, nil
+	case <-ctx.Done():
+	}
+	select {
+	case v := <-done:
+		return v, nil
+	case <-clk().After(timeout):
+		var zero T
+		return zero, &ShutdownError{Stage: stage(), Timeout: timeout}
+	}
+}
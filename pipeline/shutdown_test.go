@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Генератор, не закрывающий канал после отмены, не должен вешать Run:
+// по истечении WithShutdownTimeout Run возвращает *ShutdownError со
+// стадией generator.
+func TestRunReportsLeakedGenerator(t *testing.T) {
+	release := make(chan struct{})
+	leaked := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+		<-leaked
+	})
+	gen := func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+		defer close(leaked)
+		defer close(ch)
+		ch <- 1
+		fn(1)
+		<-release // отмену ctx генератор не замечает
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := RunWith(ctx, 2, gen, WithDelay(0), WithShutdownTimeout(50*time.Millisecond))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run вернулся через %v", elapsed)
+	}
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("получено %v, ожидалась *ShutdownError", err)
+	}
+	if shutdownErr.Stage != StageGenerator || shutdownErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("стадия %q, таймаут %v; ожидалось generator, 50ms", shutdownErr.Stage, shutdownErr.Timeout)
+	}
+	if result.StopReason != StopError || result.InputCount != 1 {
+		t.Fatalf("StopReason %v, InputCount %d; ожидалось StopError, 1", result.StopReason, result.InputCount)
+	}
+}
+
+// Воркер, который вернулся, не закрыв свой канал, не даёт сборщику
+// закрыть результирующий канал: Run сообщает о зависшей стадии fan-in.
+// Воркер, который не вернулся вовсе, Run называет по стадии worker.
+func TestRunReportsLeakedWorkerOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns bool // возвращается ли воркер, не закрыв out
+		want    string
+	}{
+		{"воркер вернулся", true, StageFanIn},
+		{"воркер завис", false, StageWorker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			t.Cleanup(func() { close(release) })
+			leaky := func(ctx context.Context, in <-chan int64, out chan<- int64, _ time.Duration, _ func(time.Duration)) {
+				for {
+					v, ok := recvCtx(ctx, in)
+					if !ok || !sendCtx(ctx, out, v) {
+						break
+					}
+				}
+				// канал закрывается только после теста, чтобы сборщик не
+				// остался висеть навсегда
+				closeOut := func() {
+					<-release
+					close(out)
+				}
+				if tt.returns {
+					go closeOut()
+					return
+				}
+				closeOut()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err := RunBounded(ctx, 2, 1_000_000, WithDelay(0),
+				WithShutdownTimeout(50*time.Millisecond),
+				func(c *config) { c.forward = leaky })
+
+			var shutdownErr *ShutdownError
+			if !errors.As(err, &shutdownErr) {
+				t.Fatalf("получено %v, ожидалась *ShutdownError", err)
+			}
+			if shutdownErr.Stage != tt.want {
+				t.Fatalf("стадия %q, ожидалась %q", shutdownErr.Stage, tt.want)
+			}
+		})
+	}
+}
+
+func TestShutdownErrorMessage(t *testing.T) {
+	err := &ShutdownError{Stage: StageWorker, Timeout: time.Second}
+	want := "конвейер не завершился за 1s после отмены: зависла стадия worker"
+	if err.Error() != want {
+		t.Fatalf("получено %q, ожидалось %q", err, want)
+	}
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// ErrSink — причина остановки генерации, когда приёмник не смог принять
+// число (см. WithSink). Исходная ошибка приёмника доступна через errors.Is
+// и errors.As.
+var ErrSink = errors.New("ошибка приёмника")
+
+// Sink — приёмник чисел с выхода конвейера (см. WithSink). Run вызывает
+// Write для каждого числа из результирующего канала из одной горутины, а
+// Close — один раз, после последнего Write.
+type Sink interface {
+	Write(v int64) error
+	Close() error
+}
+
+// AggregateSink — приёмник, накапливающий итоги в памяти так же, как
+// AggregateStats.
+type AggregateSink struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewAggregateSink создаёт пустой AggregateSink.
+func NewAggregateSink() *AggregateSink {
+	return &AggregateSink{stats: Stats{Empty: true}}
+}
+
+// Write учитывает v в итогах.
+func (s *AggregateSink) Write(v int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.add(v)
+	return nil
+}
+
+// Close ничего не делает.
+func (s *AggregateSink) Close() error { return nil }
+
+// Stats возвращает накопленные итоги.
+func (s *AggregateSink) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// NullSink — приёмник, отбрасывающий все числа.
+type NullSink struct{}
+
+// Write отбрасывает v.
+func (NullSink) Write(int64) error { return nil }
+
+// Close ничего не делает.
+func (NullSink) Close() error { return nil }
+
+// WriterSink — приёмник, записывающий числа в w по одному в строке. Запись
+// буферизуется и дописывается при Close; сам w WriterSink не закрывает.
+type WriterSink struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriterSink создаёт WriterSink, пишущий в w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: bufio.NewWriter(w)}
+}
+
+// Write записывает v отдельной строкой.
+func (s *WriterSink) Write(v int64) error {
+	s.buf = strconv.AppendInt(s.buf[:0], v, 10)
+	s.buf = append(s.buf, '\n')
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// Close дописывает буферизованные строки.
+func (s *WriterSink) Close() error {
+	return s.w.Flush()
+}
+
+// drainToSink читает in до закрытия, считая итоги, и передаёт каждое число
+// в sink, если он не nil. После первой ошибки Write числа в sink больше не
+// передаются, но in дочитывается до конца, чтобы конвейер мог завершиться;
+// stop при этом вызывается один раз с ошибкой, обёрнутой в ErrSink. Ошибка
+// Write или, если её не было, Close возвращается вместе с итогами.
+func drainToSink(in <-chan int64, sink Sink, stop func(error)) (Stats, error) {
+	s := Stats{Empty: true}
+	var err error
+	for v := range in {
+		s.add(v)
+		if sink == nil || err != nil {
+			continue
+		}
+		if werr := sink.Write(v); werr != nil {
+			err = fmt.Errorf("%w: %w", ErrSink, werr)
+			stop(err)
+		}
+	}
+
+	if sink != nil {
+		if cerr := sink.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("%w: %w", ErrSink, cerr)
+		}
+	}
+	return s, err
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAggregateSinkMatchesResult(t *testing.T) {
+	sink := NewAggregateSink()
+	result, err := RunBounded(context.Background(), 3, 200, WithDelay(0), WithSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sink.Stats()
+	if s.Count != result.OutputCount || s.Sum != result.OutputSum || s.Min != 1 || s.Max != 200 {
+		t.Fatalf("приёмник насчитал %+v, итоги %+v", s, result)
+	}
+}
+
+func TestNullSink(t *testing.T) {
+	result, err := RunBounded(context.Background(), 3, 200, WithDelay(0), WithSink(NullSink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterSinkWritesEveryValue(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := RunBounded(context.Background(), 3, 200, WithDelay(0), WithSink(NewWriterSink(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if int64(len(lines)) != result.OutputCount {
+		t.Fatalf("записано строк %d, доставлено чисел %d", len(lines), result.OutputCount)
+	}
+	var sum int64
+	for _, line := range lines {
+		v, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			t.Fatalf("строка %q не число", line)
+		}
+		sum += v
+	}
+	if sum != result.OutputSum {
+		t.Fatalf("сумма записанных чисел %d, ожидалось %d", sum, result.OutputSum)
+	}
+}
+
+func TestRunStopsOnSinkError(t *testing.T) {
+	result, err := Run(context.Background(), 2, WithDelay(0), WithSink(failingSink{}))
+	if !errors.Is(err, ErrSink) {
+		t.Fatalf("получено %v, ожидалась ErrSink", err)
+	}
+	if result.StopCause != "sink" {
+		t.Fatalf("StopCause = %q, ожидалось sink", result.StopCause)
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatalf("итоги не сходятся после ошибки приёмника: %v", err)
+	}
+}
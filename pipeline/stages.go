@@ -0,0 +1,309 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidSize сообщает, что размер пачки или окна стадии не
+// положительный.
+var ErrInvalidSize = errors.New("размер должен быть положительным")
+
+// Filter пересылает из in в out только числа, для которых keep возвращает
+// true, и закрывает out, когда закрывается in. Filter можно ставить между
+// воркером и сборщиком: он сохраняет обычные правила закрытия каналов.
+func Filter(in <-chan int64, out chan<- int64, keep func(int64) bool) {
+	defer close(out)
+
+	for v := range in {
+		if keep(v) {
+			out <- v
+		}
+	}
+}
+
+// Batch собирает числа из in в пачки по size штук и отправляет их в out.
+// Если с момента поступления первого числа неполной пачки прошло flush, она
+// отправляется досрочно; при flush <= 0 досрочной отправки нет. Когда
+// закрывается in, Batch отправляет накопленную неполную пачку и закрывает
+// out. Пустые пачки не отправляются.
+//
+// При size <= 0 Batch сразу закрывает out и возвращает ErrInvalidSize.
+func Batch(in <-chan int64, out chan<- []int64, size int, flush time.Duration) error {
+	defer close(out)
+	if size <= 0 {
+		return ErrInvalidSize
+	}
+
+	batch := make([]int64, 0, size)
+	send := func() {
+		if len(batch) > 0 {
+			out <- batch
+			batch = make([]int64, 0, size)
+		}
+	}
+
+	// timer и expired заводятся при первом числе пачки; пока пачка пуста,
+	// expired равен nil и в select никогда не срабатывает
+	var timer *time.Timer
+	var expired <-chan time.Time
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, expired = nil, nil
+		}
+	}
+	defer stopTimer()
+
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				send()
+				return nil
+			}
+			batch = append(batch, v)
+			if len(batch) == size {
+				stopTimer()
+				send()
+			} else if len(batch) == 1 && flush > 0 {
+				timer = time.NewTimer(flush)
+				expired = timer.C
+			}
+		case <-expired:
+			timer, expired = nil, nil
+			send()
+		}
+	}
+}
+
+// WorkerBatchTransform применяет f к каждой пачке из in — например, к
+// пачкам от Batch — и отправляет результат в out, чтобы преобразование
+// работало сразу с целой пачкой. f может вернуть пачку другой длины: так
+// преобразование может и отфильтровать, и размножить числа. Пустые
+// результаты не отправляются, как и у Batch. Пачку из in f может менять на
+// месте: Batch отдаёт каждую пачку в собственном слайсе. Когда закрывается
+// in, WorkerBatchTransform закрывает out.
+func WorkerBatchTransform(in <-chan []int64, out chan<- []int64, f func([]int64) []int64) {
+	defer close(out)
+	for batch := range in {
+		if res := f(batch); len(res) > 0 {
+			out <- res
+		}
+	}
+}
+
+// Chain последовательно соединяет стадии преобразования: каждая стадия
+// работает в своей горутине, читает выход предыдущей и применяет к каждому
+// числу свою функцию. Chain возвращает выходной канал последней стадии.
+// Закрытие in каскадом закрывает выходы всех стадий от первой к последней.
+// Без стадий Chain возвращает in.
+func Chain(in <-chan int64, stages ...func(int64) int64) <-chan int64 {
+	for _, stage := range stages {
+		out := make(chan int64)
+		go func(in <-chan int64, stage func(int64) int64) {
+			defer close(out)
+
+			for v := range in {
+				out <- stage(v)
+			}
+		}(in, stage)
+		in = out
+	}
+	return in
+}
+
+// Dedup пересылает из in в out только первое вхождение каждого числа и
+// закрывает out, когда закрывается in. Dedup помнит все встреченные числа,
+// поэтому его память не ограничена; для длинных потоков подходит
+// DedupWindow.
+func Dedup(in <-chan int64, out chan<- int64) {
+	defer close(out)
+
+	seen := make(map[int64]struct{})
+	for v := range in {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out <- v
+	}
+}
+
+// DedupWindow — вариант Dedup, который помнит только window последних
+// различных чисел: число, вытесненное из окна, при повторной встрече
+// пересылается снова. Повтор числа из окна не продлевает его пребывание в
+// окне.
+//
+// При window <= 0 DedupWindow сразу закрывает out и возвращает
+// ErrInvalidSize.
+func DedupWindow(in <-chan int64, out chan<- int64, window int) error {
+	defer close(out)
+	if window <= 0 {
+		return ErrInvalidSize
+	}
+
+	// order — кольцевой буфер чисел окна в порядке поступления, next —
+	// позиция самого старого из них, когда окно заполнено
+	seen := make(map[int64]struct{}, window)
+	order := make([]int64, 0, window)
+	next := 0
+	for v := range in {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		if len(order) < window {
+			order = append(order, v)
+		} else {
+			delete(seen, order[next])
+			order[next] = v
+			next = (next + 1) % window
+		}
+		seen[v] = struct{}{}
+		out <- v
+	}
+	return nil
+}
+
+// Tee копирует каждое число из in во все n возвращённых каналов и закрывает
+// их, когда закрывается in. Следующее число читается из in только после
+// того, как текущее приняли все потребители, поэтому каждая ветка получает
+// полную последовательность в исходном порядке, а самый медленный
+// потребитель задаёт скорость остальным. Каждую ветку обязательно нужно
+// читать до закрытия, иначе Tee заблокируется. При n < 1 Tee возвращает
+// один канал.
+func Tee(in <-chan int64, n int) []<-chan int64 {
+	n = max(n, 1)
+
+	chans := make([]chan int64, n)
+	outs := make([]<-chan int64, n)
+	for i := range chans {
+		chans[i] = make(chan int64)
+		outs[i] = chans[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+
+		for v := range in {
+			for _, ch := range chans {
+				ch <- v
+			}
+		}
+	}()
+
+	return outs
+}
+
+// MovingAverage для каждого числа из in отправляет в out среднее последних
+// window чисел, включая текущее; пока окно не заполнилось, усредняются все
+// числа, полученные к этому моменту. Когда закрывается in, MovingAverage
+// закрывает out.
+//
+// При window <= 0 MovingAverage сразу закрывает out и возвращает
+// ErrInvalidSize.
+func MovingAverage(in <-chan int64, out chan<- float64, window int) error {
+	defer close(out)
+	if window <= 0 {
+		return ErrInvalidSize
+	}
+
+	// values — кольцевой буфер окна, next — позиция самого старого числа,
+	// когда окно заполнено
+	values := make([]int64, 0, window)
+	next := 0
+	var sum int64
+	for v := range in {
+		if len(values) < window {
+			values = append(values, v)
+		} else {
+			sum -= values[next]
+			values[next] = v
+			next = (next + 1) % window
+		}
+		sum += v
+		out <- float64(sum) / float64(len(values))
+	}
+	return nil
+}
+
+// CumulativeSum для каждого числа из in отправляет в out сумму всех чисел,
+// полученных к этому моменту, включая текущее: для 1, 2, 3 это 1, 3, 6.
+// Последнее отправленное значение равно сумме, которую считают Aggregate и
+// функция fn генератора, так что по нему можно сверять итоги с потоком.
+// Когда закрывается in, CumulativeSum закрывает out.
+//
+// Промежуточные суммы имеют смысл только для упорядоченного потока, поэтому
+// CumulativeSum стоит ставить после OrderedFanIn, а не после FanIn. При
+// выходе за пределы int64 сумма переходит через границу по модулю 2^64, как
+// Result.InputSum при Overflow; проверка переполнения остаётся за
+// получателем.
+func CumulativeSum(in <-chan int64, out chan<- int64) {
+	defer close(out)
+
+	var sum int64
+	for v := range in {
+		sum += v
+		out <- sum
+	}
+}
+
+// Map применяет f к каждому значению из in и отправляет результат в out;
+// когда закрывается in, Map закрывает out. Так числа конвейера можно
+// превратить в значения другого типа, например в события, сразу после
+// Worker или сборщика.
+func Map[I, O any](in <-chan I, out chan<- O, f func(I) O) {
+	defer close(out)
+
+	for v := range in {
+		out <- f(v)
+	}
+}
+
+// MapWithClock работает как Map, но передаёт в f ещё и время получения
+// значения по часам c, чтобы превращать числа в события с отметкой времени.
+// С FakeClock отметки полностью определяются тестом. При c == nil
+// используются часы пакета (см. SetClock).
+func MapWithClock[I, O any](in <-chan I, out chan<- O, c Clock, f func(v I, at time.Time) O) {
+	defer close(out)
+	if c == nil {
+		c = clk()
+	}
+
+	for v := range in {
+		out <- f(v, c.Now())
+	}
+}
+
+// Pace пересылает числа из in в out, делая после каждого паузу, которая
+// удваивается от base до max: base, 2*base, 4*base и т.д. Так конвейер
+// можно бережно дочитывать в приёмник с ограничением скорости. Паузы
+// отсчитываются по часам пакета (см. SetClock); при base <= 0 пауз нет, а
+// при max < base все паузы равны base.
+//
+// Отмена ctx сразу прерывает текущую паузу, и дальше Pace пересылает
+// оставшиеся числа без пауз. Когда закрывается in, Pace закрывает out.
+func Pace(ctx context.Context, in <-chan int64, out chan<- int64, base, max time.Duration) {
+	defer close(out)
+
+	delay := base
+	for v := range in {
+		out <- v
+		if delay <= 0 || ctx.Err() != nil {
+			continue
+		}
+
+		select {
+		case <-clk().After(delay):
+		case <-ctx.Done():
+		}
+		if delay < max {
+			delay = min(delay*2, max)
+		}
+	}
+}
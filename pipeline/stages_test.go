@@ -0,0 +1,287 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// values возвращает закрытый буферизованный канал с числами vs.
+func values(vs ...int64) <-chan int64 {
+	ch := make(chan int64, len(vs))
+	for _, v := range vs {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// readAll читает канал ch до закрытия.
+func readAll[T any](ch <-chan T) []T {
+	var got []T
+	for v := range ch {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestFilterKeepsEven(t *testing.T) {
+	out := make(chan int64)
+	go Filter(values(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), out, func(v int64) bool { return v%2 == 0 })
+	if got, want := readAll(out), []int64{2, 4, 6, 8, 10}; !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+}
+
+func TestBatchBySize(t *testing.T) {
+	out := make(chan []int64)
+	go Batch(values(1, 2, 3, 4, 5, 6, 7), out, 3, 0)
+	got := readAll(out)
+	want := [][]int64{{1, 2, 3}, {4, 5, 6}, {7}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]int64]) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+}
+
+func TestBatchFlushesOnTimeout(t *testing.T) {
+	in := make(chan int64)
+	out := make(chan []int64)
+	go Batch(in, out, 10, 20*time.Millisecond)
+	in <- 1
+	in <- 2
+
+	// неполная пачка должна уйти по таймеру, хотя in ещё открыт
+	select {
+	case got := <-out:
+		if !slices.Equal(got, []int64{1, 2}) {
+			t.Fatalf("получено %v, ожидалось [1 2]", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("неполная пачка не отправлена по таймеру")
+	}
+	close(in)
+	if rest := readAll(out); len(rest) != 0 {
+		t.Fatalf("после закрытия in отправлены пачки %v", rest)
+	}
+}
+
+func TestBatchInvalidSize(t *testing.T) {
+	out := make(chan []int64)
+	if err := Batch(values(1), out, 0, 0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("получено %v, ожидалась ErrInvalidSize", err)
+	}
+	if _, ok := <-out; ok {
+		t.Fatal("out не закрыт")
+	}
+}
+
+func TestChainAppliesStagesInOrder(t *testing.T) {
+	double := func(v int64) int64 { return v * 2 }
+	inc := func(v int64) int64 { return v + 1 }
+	got := readAll(Chain(values(1, 2, 3, 4, 5), double, inc))
+	if want := []int64{3, 5, 7, 9, 11}; !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+
+	if got := readAll(Chain(values(1, 2))); !slices.Equal(got, []int64{1, 2}) {
+		t.Fatalf("без стадий получено %v, ожидалось [1 2]", got)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	out := make(chan int64)
+	go Dedup(values(1, 2, 1, 3, 2, 4, 4), out)
+	if got, want := readAll(out), []int64{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+}
+
+func TestDedupWindowForgetsEvicted(t *testing.T) {
+	out := make(chan int64)
+	// окно из двух чисел: 3 вытесняет 1, поэтому следующая 1 проходит
+	// снова, а 3 всё ещё в окне и отбрасывается
+	go DedupWindow(values(1, 2, 1, 3, 1, 3), out, 2)
+	if got, want := readAll(out), []int64{1, 2, 3, 1}; !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+
+	if err := DedupWindow(values(1), make(chan int64), 0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("при window 0 получено %v, ожидалась ErrInvalidSize", err)
+	}
+}
+
+func TestTeeCopiesFullSequence(t *testing.T) {
+	want := make([]int64, 100)
+	for i := range want {
+		want[i] = int64(i + 1)
+	}
+	branches := Tee(values(want...), 2)
+	results := make(chan []int64, len(branches))
+	for _, b := range branches {
+		go func() { results <- readAll(b) }()
+	}
+	for range branches {
+		if got := <-results; !slices.Equal(got, want) {
+			t.Fatalf("ветка получила %v, ожидалось 1..100", got)
+		}
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	out := make(chan float64)
+	done := make(chan error, 1)
+	go func() { done <- MovingAverage(values(1, 2, 3, 4, 5, 6), out, 3) }()
+	// readAll возвращается только после закрытия out
+	got := readAll(out)
+	if want := []float64{1, 1.5, 2, 3, 4, 5}; !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	out = make(chan float64)
+	if err := MovingAverage(values(1), out, 0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("при window 0 получено %v, ожидалась ErrInvalidSize", err)
+	}
+	if _, ok := <-out; ok {
+		t.Fatal("при window 0 out не закрыт")
+	}
+}
+
+func TestMapAfterWorker(t *testing.T) {
+	type event struct {
+		Value  int64
+		Square int64
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int64)
+	forwarded := make(chan int64)
+	events := make(chan event)
+	go GeneratorN(ctx, in, 5, func(int64) {})
+	go WorkerWithDelay(ctx, in, forwarded, 0)
+	go Map(forwarded, events, func(v int64) event { return event{Value: v, Square: v * v} })
+
+	got := readAll(events)
+	want := []event{{1, 1}, {2, 4}, {3, 9}, {4, 16}, {5, 25}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+}
+
+func TestWorkerBatchTransform(t *testing.T) {
+	double := func(batch []int64) []int64 {
+		for i := range batch {
+			batch[i] *= 2
+		}
+		return batch
+	}
+	// оставляет только числа больше 4; пачка из одних малых чисел
+	// становится пустой и не отправляется
+	large := func(batch []int64) []int64 {
+		kept := batch[:0]
+		for _, v := range batch {
+			if v > 4 {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+
+	tests := []struct {
+		name string
+		f    func([]int64) []int64
+		want [][]int64
+	}{
+		{"удвоение", double, [][]int64{{2, 4, 6}, {8, 10, 12}, {14}}},
+		{"отбор", large, [][]int64{{5, 6}, {7}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := make(chan []int64)
+			out := make(chan []int64)
+			go Batch(values(1, 2, 3, 4, 5, 6, 7), batches, 3, 0)
+			go WorkerBatchTransform(batches, out, tt.f)
+			if got := readAll(out); !slices.EqualFunc(got, tt.want, slices.Equal[[]int64]) {
+				t.Fatalf("получено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaceDoublesDelayUpToMax(t *testing.T) {
+	c := useFakeClock(t)
+	out := make(chan int64)
+	go Pace(context.Background(), values(1, 2, 3, 4, 5), out, 10*time.Millisecond, 40*time.Millisecond)
+
+	for _, delay := range []time.Duration{10, 20, 40, 40} {
+		delay *= time.Millisecond
+		<-out
+		waitWaiters(t, c, 1)
+		c.Advance(delay - time.Nanosecond)
+		if c.Waiters() != 1 {
+			t.Fatalf("пауза закончилась раньше %v", delay)
+		}
+		c.Advance(time.Nanosecond)
+		if c.Waiters() != 0 {
+			t.Fatalf("пауза не закончилась через %v", delay)
+		}
+	}
+	<-out
+	waitWaiters(t, c, 1)
+	c.Advance(40 * time.Millisecond)
+	if _, ok := <-out; ok {
+		t.Fatal("out не закрыт после закрытия in")
+	}
+}
+
+func TestPaceCancelCutsDelayShort(t *testing.T) {
+	c := useFakeClock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan int64)
+	go Pace(ctx, values(1, 2, 3), out, time.Hour, time.Hour)
+
+	<-out
+	waitWaiters(t, c, 1)
+	cancel()
+	// часы стоят, но после отмены Pace досылает остальное без пауз
+	if got := readAll(out); !slices.Equal(got, []int64{2, 3}) {
+		t.Fatalf("после отмены получено %v, ожидалось [2 3]", got)
+	}
+}
+
+func TestCumulativeSum(t *testing.T) {
+	out := make(chan int64)
+	go CumulativeSum(values(1, 2, 3), out)
+	if got := readAll(out); !slices.Equal(got, []int64{1, 3, 6}) {
+		t.Fatalf("получено %v, ожидалось [1 3 6]", got)
+	}
+}
+
+func TestMapWithClockStampsFakeTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	in := make(chan int64)
+	out := make(chan time.Time)
+	go MapWithClock(in, out, c, func(_ int64, at time.Time) time.Time { return at })
+
+	// между числами часы сдвигаются на step, так что отметки времени — это
+	// арифметическая прогрессия с первым членом start
+	const step = 250 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		in <- int64(i)
+		if at, want := <-out, start.Add(time.Duration(i)*step); !at.Equal(want) {
+			t.Fatalf("отметка %d: %v, ожидалось %v", i, at, want)
+		}
+		c.Advance(step)
+	}
+	close(in)
+	if _, ok := <-out; ok {
+		t.Fatal("out не закрыт после закрытия in")
+	}
+}
@@ -0,0 +1,20 @@
+package pipeline
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Имена спанов OpenTelemetry, которые создаёт Run.
+const (
+	spanRun       = "pipeline.Run"
+	spanGenerator = "pipeline.Generator"
+	spanWorker    = "pipeline.Worker"
+	spanCollector = "pipeline.Collector"
+)
+
+// tracer возвращает трассировщик пакета из глобального провайдера
+// OpenTelemetry. Пока провайдер не настроен, спаны ничего не делают.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/pavel-a-borisov/go-project-sprint-9/pipeline")
+}
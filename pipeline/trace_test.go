@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans направляет спаны пакета в память до конца теста.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// spanAttr возвращает значение атрибута key спана s.
+func spanAttr(s tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRunSpans(t *testing.T) {
+	exporter := recordSpans(t)
+	const workers = 3
+	result, err := RunBounded(context.Background(), workers, 30, WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := map[string][]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	if n := len(byName[spanRun]); n != 1 {
+		t.Fatalf("спанов %s: %d, ожидался 1", spanRun, n)
+	}
+	root := byName[spanRun][0]
+	if root.Parent.IsValid() {
+		t.Fatalf("у спана %s есть родитель", spanRun)
+	}
+
+	want := map[string]int{spanGenerator: 1, spanWorker: workers, spanCollector: 1}
+	for name, n := range want {
+		if len(byName[name]) != n {
+			t.Fatalf("спанов %s: %d, ожидалось %d", name, len(byName[name]), n)
+		}
+		for _, s := range byName[name] {
+			if s.Parent.SpanID() != root.SpanContext.SpanID() {
+				t.Fatalf("спан %s не дочерний для %s", name, spanRun)
+			}
+			if s.SpanContext.TraceID() != root.SpanContext.TraceID() {
+				t.Fatalf("спан %s в другой трассе", name)
+			}
+		}
+	}
+
+	seen := map[int64]bool{}
+	for _, s := range byName[spanWorker] {
+		v, ok := spanAttr(s, "worker.index")
+		if !ok {
+			t.Fatalf("у спана %s нет атрибута worker.index", spanWorker)
+		}
+		seen[v.AsInt64()] = true
+	}
+	if len(seen) != workers {
+		t.Fatalf("индексы воркеров %v, ожидалось %d разных", seen, workers)
+	}
+
+	v, ok := spanAttr(byName[spanCollector][0], "processed.count")
+	if !ok || v.AsInt64() != result.OutputCount {
+		t.Fatalf("processed.count = %v, ожидалось %d", v.Emit(), result.OutputCount)
+	}
+}
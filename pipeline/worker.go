@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultDelay — пауза, которую Worker делает после пересылки каждого числа.
+const DefaultDelay = 1 * time.Millisecond
+
+// Worker читает число из канала in и пишет его в канал out, делая после
+// каждой записи паузу DefaultDelay. Когда канал in закрывается,
+// Worker закрывает канал out и завершает работу.
+//
+// При отмене ctx Worker прекращает пересылку, не дожидаясь закрытия in, и
+// тоже закрывает out; число, которое он успел прочитать, при этом теряется.
+// С context.Background() Worker работает до закрытия in.
+func Worker(ctx context.Context, in <-chan int64, out chan<- int64) {
+	WorkerWithDelay(ctx, in, out, DefaultDelay)
+}
+
+// WorkerWithDelay работает так же, как Worker, но делает после каждой
+// пересылки паузу delay. При delay <= 0 пауза не делается вовсе, что даёт
+// максимальную пропускную способность; большие значения имитируют
+// медленного потребителя.
+func WorkerWithDelay(ctx context.Context, in <-chan int64, out chan<- int64, delay time.Duration) {
+	forward(ctx, in, out, delay, nil)
+}
+
+// WorkerFloat — вариант Worker для чисел float64.
+func WorkerFloat(ctx context.Context, in <-chan float64, out chan<- float64) {
+	forward(ctx, in, out, DefaultDelay, nil)
+}
+
+// WorkerTimed работает так же, как Worker, и дополнительно измеряет, сколько
+// времени каждое число провело в воркере: от получения из in до отправки в
+// out, включая паузу после отправки. Длительность передаётся в hist;
+// при hist == nil измерение не выполняется.
+func WorkerTimed(ctx context.Context, in <-chan int64, out chan<- int64, hist func(time.Duration)) {
+	forward(ctx, in, out, DefaultDelay, hist)
+}
+
+// WorkerFunc — воркер с индексом index, который читает числа из канала in и
+// применяет к каждому transform: при успехе результат отправляется в out,
+// при ошибке в errs отправляется ошибка, дополненная индексом воркера и
+// исходным числом ("worker 2: value 15: ..."; исходная ошибка доступна
+// через errors.Unwrap), а число пропускается. Паника в transform
+// превращается в ошибку *PanicError и обрабатывается так же. Когда
+// закрывается in или отменяется ctx, WorkerFunc закрывает out и завершает
+// работу.
+//
+// Канал errs WorkerFunc никогда не закрывает: обычно его делят несколько
+// воркеров, и закрыть его должен владелец после завершения всех воркеров,
+// иначе возможна паника из-за повторного закрытия. Так делает
+// ErrorCollector.
+func WorkerFunc(ctx context.Context, index int, in <-chan int64, out chan<- int64, errs chan<- error, transform func(int64) (int64, error)) {
+	defer closeOwned(ctx, out, StageWorker)
+
+	for {
+		v, ok := recvCtx(ctx, in)
+		if !ok {
+			return
+		}
+
+		res, err := safeTransform(ctx, transform, v)
+		if err != nil {
+			if !sendCtx(ctx, errs, fmt.Errorf("worker %d: value %d: %w", index, v, err)) {
+				return
+			}
+			continue
+		}
+
+		if !sendCtx(ctx, out, res) {
+			return
+		}
+	}
+}
+
+// forward пересылает значения из in в out с паузой delay после каждой
+// записи, пока не закроется in или не отменится ctx, и затем закрывает out.
+// Если hist не nil, в неё передаётся время обработки каждого значения.
+func forward[T any](ctx context.Context, in <-chan T, out chan<- T, delay time.Duration, hist func(time.Duration)) {
+	defer closeOwned(ctx, out, StageWorker)
+
+	for {
+		v, ok := recvCtx(ctx, in)
+		if !ok {
+			return
+		}
+		var start time.Time
+		if hist != nil {
+			start = clk().Now()
+		}
+
+		if !sendCtx(ctx, out, v) {
+			return
+		}
+		if delay > 0 {
+			clk().Sleep(delay)
+		}
+		if hist != nil {
+			hist(clk().Now().Sub(start))
+		}
+	}
+}
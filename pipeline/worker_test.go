@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errOdd = errors.New("нечётное число")
+
+// rejectOdd пропускает чётные числа и отклоняет нечётные.
+func rejectOdd(v int64) (int64, error) {
+	if v%2 != 0 {
+		return 0, errOdd
+	}
+	return v, nil
+}
+
+func TestWorkerFuncHalfErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 100
+	in := make(chan int64)
+	out := make(chan int64)
+	errs := make(chan error)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	go WorkerFunc(ctx, 0, in, out, errs, rejectOdd)
+
+	var wg sync.WaitGroup
+	var failed int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for err := range errs {
+			if !errors.Is(err, errOdd) {
+				t.Errorf("неожиданная ошибка: %v", err)
+			}
+			failed++
+		}
+	}()
+	var passed int
+	for v := range out {
+		if v%2 != 0 {
+			t.Fatalf("пропущено нечётное число %d", v)
+		}
+		passed++
+	}
+	// out закрыт, значит воркер завершился, а errs он не закрывает
+	close(errs)
+	wg.Wait()
+
+	if passed != n/2 || failed != n/2 {
+		t.Fatalf("в out %d чисел, в errs %d ошибок, ожидалось по %d", passed, failed, n/2)
+	}
+}
+
+func TestWorkerTimedIncludesDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const n = 20
+	var durations []time.Duration
+	in := make(chan int64)
+	out := make(chan int64)
+	go GeneratorN(ctx, in, n, func(int64) {})
+	go WorkerTimed(ctx, in, out, func(d time.Duration) { durations = append(durations, d) })
+	for range out {
+	}
+
+	// out закрыт после последнего вызова hist
+	if len(durations) != n {
+		t.Fatalf("hist вызвана %d раз, ожидалось %d", len(durations), n)
+	}
+	for i, d := range durations {
+		if d < DefaultDelay {
+			t.Fatalf("длительность %d — %v, меньше паузы %v", i, d, DefaultDelay)
+		}
+	}
+}
+
+// Пауза Worker идёт по часам пакета: пока FakeClock не сдвинут, воркер не
+// берёт следующее число, и тест не ждёт настоящего времени.
+func TestWorkerSleepsOnPackageClock(t *testing.T) {
+	c := useFakeClock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int64)
+	out := make(chan int64)
+	go Worker(ctx, in, out)
+	for v := int64(1); v <= 3; v++ {
+		in <- v
+		if got := <-out; got != v {
+			t.Fatalf("получено %d, ожидалось %d", got, v)
+		}
+		waitWaiters(t, c, 1)
+		select {
+		case in <- 100:
+			t.Fatal("воркер принял число, не дождавшись паузы")
+		case <-time.After(5 * time.Millisecond):
+		}
+		c.Advance(DefaultDelay)
+	}
+	close(in)
+	if _, ok := <-out; ok {
+		t.Fatal("out не закрыт после закрытия in")
+	}
+}
+
+func TestWorkerFuncWrapsError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int64, 1)
+	out := make(chan int64)
+	errs := make(chan error, 1)
+	in <- 15
+	close(in)
+	go WorkerFunc(ctx, 2, in, out, errs, rejectOdd)
+	readAll(out)
+
+	err := <-errs
+	if want := "worker 2: value 15: " + errOdd.Error(); err.Error() != want {
+		t.Fatalf("получено %q, ожидалось %q", err, want)
+	}
+	if errors.Unwrap(err) != errOdd {
+		t.Fatalf("errors.Unwrap вернула %v, ожидалась исходная ошибка", errors.Unwrap(err))
+	}
+}
@@ -2,89 +2,544 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"sync"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/metrics"
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+	"github.com/pavel-a-borisov/go-project-sprint-9/sse"
 )
 
-// Generator генерирует последовательность чисел 1,2,3 и т.д. и
-// отправляет их в канал ch. При этом после записи в канал для каждого числа
-// вызывается функция fn. Она служит для подсчёта количества и суммы
-// сгенерированных чисел.
-func Generator(ctx context.Context, ch chan<- int64, fn func(int64)) {
-	// 1. Функция Generator
-	// ...
+func main() {
+	workers := flag.Int("workers", pipeline.DefaultWorkers, "количество обрабатывающих горутин и каналов (>= 1)")
+	inbuf := flag.Int("inbuf", 0, "ёмкость буфера входного канала между генератором и воркерами (>= 0); большой буфер смещает разбивку по каналам")
+	outbuf := flag.Int("outbuf", -1, "ёмкость буфера результирующего канала (>= 0); по умолчанию равна -workers")
+	queueDepth := flag.Int("queue-depth", 0, "глубина собственной очереди перед каждым воркером; 0 — общий входной канал без очередей")
+	dropPolicy := flag.String("drop-policy", pipeline.DropBlock.String(), "политика переполнения очереди воркера: block, drop-newest или drop-oldest")
+	duration := flag.Duration("duration", time.Second, "время генерации чисел, например 500ms, 2s, 1m")
+	ramp := flag.Duration("ramp", 0, "время, за которое воркеры запускаются по одному через равные промежутки; 0 — все сразу")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 0, "сколько ждать завершения конвейера после окончания генерации; 0 — без ограничения")
+	deadline := flag.String("deadline", "", "момент окончания генерации в формате RFC3339, например 2024-01-02T15:04:05Z; взаимоисключающий с -duration")
+	output := flag.String("output", outputText, "формат вывода итогов: text, json или csv")
+	pretty := flag.Bool("pretty", false, "выводить JSON-итоги в несколько строк с отступами; для text и csv не действует")
+	locale := flag.String("locale", "ru", "язык подписей текстовых итогов: ru или en")
+	outputFile := flag.String("output-file", "", "файл для итогов; если пусто, итоги выводятся в stdout")
+	sink := flag.String("sink", sinkAggregate, "приёмник чисел с выхода конвейера: aggregate (только итоги), null (отбрасывать) или writer (по числу в строке в -sink-file)")
+	sinkFile := flag.String("sink-file", "", "файл для приёмника writer; если пусто, числа выводятся в stdout")
+	metricsAddr := flag.String("metrics-addr", "", "адрес HTTP-сервера с метриками Prometheus на /metrics; если пусто, сервер не запускается")
+	expvarAddr := flag.String("expvar", "", "адрес HTTP-сервера со счётчиками expvar на /debug/vars; если пусто, сервер не запускается")
+	sseAddr := flag.String("sse-addr", "", "адрес HTTP-сервера, транслирующего числа на /events по SSE; если пусто, сервер не запускается")
+	runID := flag.String("run-id", "", "идентификатор запуска для журнала (атрибут run_id) и меток метрик Prometheus; если пусто, не используется")
+	lockThreads := flag.Bool("lock-threads", false, "закрепить горутину каждого воркера за потоком ОС (runtime.LockOSThread); для замеров планирования")
+	capture := flag.String("capture", "", "файл, в который записываются сгенерированные числа по одному в строке")
+	replay := flag.String("replay", "", "файл с числами по одному в строке, записанный через -capture; числа берутся из него вместо счётчика")
+	cpuProfile := flag.String("cpuprofile", "", "файл для профиля процессора на время работы конвейера")
+	memProfile := flag.String("memprofile", "", "файл для профиля памяти после работы конвейера")
+	values := flag.Int64("values", 0, "сгенерировать ровно столько чисел и остановиться, если раньше не истечёт -duration; 0 — без ограничения")
+	verifyRuns := flag.Int("verify-determinism", 0, "запустить конвейер столько раз на -values чисел без паузы в воркерах и проверить, что выходы совпадают; -duration ограничивает все запуски вместе")
+	generators := flag.Int("generators", 1, "количество горутин генератора, вместе отправляющих числа 1,2,3 и т.д. (>= 1)")
+	seed := flag.Uint64("seed", 0, "если задан, генерировать псевдослучайные числа с этим зерном вместо счётчика")
+	quiet := flag.Bool("quiet", false, "не печатать текстовые итоги; форматы json и csv выводятся как обычно, проверка итогов выполняется всегда")
+	progress := flag.Bool("progress", false, "раз в секунду печатать в stderr количество сгенерированных чисел и разбивку по каналам")
+	balanceReport := flag.Bool("balance-report", false, "после итогов напечатать в stderr оценку равномерности разбивки по каналам: индекс Джейна и хи-квадрат")
+	logLevel := slog.LevelWarn
+	flag.TextVar(&logLevel, "log-level", logLevel, "уровень журнала событий в stderr: DEBUG, INFO, WARN или ERROR")
+	flag.Parse()
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -workers должен быть не меньше 1, получено %d\n", *workers)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *inbuf < 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -inbuf должен быть неотрицательным, получено %d\n", *inbuf)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *outbuf < -1 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -outbuf должен быть неотрицательным, получено %d\n", *outbuf)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *queueDepth < 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -queue-depth должен быть неотрицательным, получено %d\n", *queueDepth)
+		flag.Usage()
+		os.Exit(2)
+	}
+	policy, err := pipeline.ParseDropPolicy(*dropPolicy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: -drop-policy: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	seedSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	if *generators < 1 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -generators должен быть не меньше 1, получено %d\n", *generators)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *generators > 1 && (seedSet || *replay != "") {
+		fmt.Fprintln(os.Stderr, "Ошибка: -generators нельзя сочетать с -seed и -replay")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *values < 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -values должен быть неотрицательным, получено %d\n", *values)
+		flag.Usage()
+		os.Exit(2)
+	}
+	// проверка детерминизма всегда идёт на счётчике RunBounded, поэтому
+	// другие источники чисел с ней не сочетаются
+	if *verifyRuns > 0 && (seedSet || *replay != "" || *generators > 1) {
+		fmt.Fprintln(os.Stderr, "Ошибка: -verify-determinism нельзя сочетать с -seed, -replay и -generators")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *values > 0 && (*generators > 1 || *replay != "") {
+		fmt.Fprintln(os.Stderr, "Ошибка: -values нельзя сочетать с -generators и -replay")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *verifyRuns < 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -verify-determinism должен быть неотрицательным, получено %d\n", *verifyRuns)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *verifyRuns > 0 && *values == 0 {
+		fmt.Fprintln(os.Stderr, "Ошибка: -verify-determinism требует -values")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if seedSet && *replay != "" {
+		fmt.Fprintln(os.Stderr, "Ошибка: -seed и -replay нельзя задавать одновременно")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *duration <= 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -duration должен быть положительным, получено %v\n", *duration)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *ramp < 0 {
+		fmt.Fprintf(os.Stderr, "Ошибка: -ramp должен быть неотрицательным, получено %v\n", *ramp)
+		flag.Usage()
+		os.Exit(2)
+	}
+	var deadlineAt time.Time
+	if *deadline != "" {
+		durationSet := false
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "duration" {
+				durationSet = true
+			}
+		})
+		if durationSet {
+			fmt.Fprintln(os.Stderr, "Ошибка: -deadline и -duration нельзя задавать одновременно")
+			flag.Usage()
+			os.Exit(2)
+		}
+		t, err := time.Parse(time.RFC3339, *deadline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: -deadline должен быть в формате RFC3339, получено %q\n", *deadline)
+			flag.Usage()
+			os.Exit(2)
+		}
+		deadlineAt = t
+	}
+	if *output != outputText && *output != outputJSON && *output != outputCSV {
+		fmt.Fprintf(os.Stderr, "Ошибка: -output должен быть text, json или csv, получено %q\n", *output)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *sink != sinkAggregate && *sink != sinkNull && *sink != sinkWriter {
+		fmt.Fprintf(os.Stderr, "Ошибка: -sink должен быть aggregate, null или writer, получено %q\n", *sink)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	labels, ok := locales[*locale]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Ошибка: -locale должен быть ru или en, получено %q\n", *locale)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config{
+		workers:     *workers,
+		inbuf:       *inbuf,
+		outbuf:      *outbuf,
+		duration:    *duration,
+		queueDepth:  *queueDepth,
+		dropPolicy:  policy,
+		deadline:    deadlineAt,
+		shutdown:    *shutdownTimeout,
+		ramp:        *ramp,
+		output:      outputFormat(*output, *pretty),
+		outputFile:  *outputFile,
+		labels:      labels,
+		sink:        *sink,
+		sinkFile:    *sinkFile,
+		metricsAddr: *metricsAddr,
+		expvarAddr:  *expvarAddr,
+		sseAddr:     *sseAddr,
+		runID:       *runID,
+		lockThreads: *lockThreads,
+		capture:     *capture,
+		replay:      *replay,
+		values:      *values,
+		verifyRuns:  *verifyRuns,
+		generators:  *generators,
+		seedSet:     seedSet,
+		seed:        *seed,
+		cpuProfile:  *cpuProfile,
+		memProfile:  *memProfile,
+		quiet:       *quiet,
+		progress:    *progress,
+		balance:     *balanceReport,
+		logLevel:    logLevel,
+	}
+	if err := run(cfg); err != nil {
+		log.Fatalf("Ошибка: %v\n", err)
+	}
 }
 
-// Worker читает число из канала in и пишет его в канал out.
-func Worker(in <-chan int64, out chan<- int64) {
-	// 2. Функция Worker
-	// ...
+// config — параметры запуска, разобранные из флагов командной строки.
+type config struct {
+	workers     int
+	inbuf       int
+	outbuf      int
+	duration    time.Duration
+	queueDepth  int
+	dropPolicy  pipeline.DropPolicy
+	deadline    time.Time // если не нулевой, используется вместо duration
+	shutdown    time.Duration
+	ramp        time.Duration
+	output      string
+	outputFile  string
+	labels      pipeline.Labels
+	sink        string
+	sinkFile    string
+	metricsAddr string
+	expvarAddr  string
+	sseAddr     string
+	runID       string
+	lockThreads bool
+	capture     string
+	replay      string
+	values      int64 // если больше нуля, генерируется не больше values чисел
+	verifyRuns  int   // если больше нуля, вместо обычного запуска проверяется детерминизм
+	generators  int
+	seedSet     bool
+	seed        uint64
+	cpuProfile  string
+	memProfile  string
+	quiet       bool
+	progress    bool
+	balance     bool
+	logLevel    slog.Level
 }
 
-func main() {
-	chIn := make(chan int64)
+// run запускает конвейер с параметрами cfg и выводит итоги. Все ресурсы —
+// HTTP-серверы и файл итогов — освобождаются до возврата, в том числе когда
+// run возвращает ошибку.
+func run(cfg config) (err error) {
+	pipeline.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.logLevel})))
 
-	// 3. Создание контекста
-	// ...
+	// SIGINT и SIGTERM отменяют тот же контекст, что и таймаут: генерация
+	// останавливается, конвейер дочитывается и итоги печатаются как обычно;
+	// что сработает раньше, то и завершает генерацию
+	sigCtx, stopSignals := notifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
 
-	// для проверки будем считать количество и сумму отправленных чисел
-	var inputSum int64   // сумма сгенерированных чисел
-	var inputCount int64 // количество сгенерированных чисел
+	ctx, cancel := deadlineContext(sigCtx, cfg)
+	defer cancel()
+	if cfg.runID != "" {
+		ctx = pipeline.WithRunID(ctx, cfg.runID)
+	}
 
-	// генерируем числа, считая параллельно их количество и сумму
-	go Generator(ctx, chIn, func(i int64) {
-		inputSum += i
-		inputCount++
-	})
+	opts := []pipeline.Option{
+		pipeline.WithInputBuffer(cfg.inbuf),
+		pipeline.WithOutputBuffer(cfg.outbuf),
+		pipeline.WithShutdownTimeout(cfg.shutdown),
+		pipeline.WithWorkerQueue(cfg.queueDepth, cfg.dropPolicy),
+		pipeline.WithRamp(cfg.ramp),
+	}
+	if cfg.lockThreads {
+		opts = append(opts, pipeline.WithLockThreads())
+	}
+	s, closeSink, err := openSink(cfg.sink, cfg.sinkFile)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, pipeline.WithSink(s))
+	defer func() {
+		if cerr := closeSink(); err == nil {
+			err = cerr
+		}
+	}()
 
-	const NumOut = 5 // количество обрабатывающих горутин и каналов
-	// outs — слайс каналов, куда будут записываться числа из chIn
-	outs := make([]chan int64, NumOut)
-	for i := 0; i < NumOut; i++ {
-		// создаём каналы и для каждого из них вызываем горутину Worker
-		outs[i] = make(chan int64)
-		go Worker(chIn, outs[i])
+	gen := pipeline.Generator
+	if cfg.generators > 1 {
+		gen = func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+			pipeline.GeneratorParallel(ctx, ch, cfg.generators, fn)
+		}
 	}
+	if cfg.seedSet {
+		rng := rand.New(rand.NewPCG(cfg.seed, 0))
+		gen = func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+			pipeline.GeneratorRandom(ctx, ch, rng, fn)
+		}
+		if cfg.values > 0 {
+			// с -values запуск с тем же -seed повторяет итоги, если
+			// -duration хватает на все числа
+			gen = func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+				pipeline.GeneratorRandomN(ctx, ch, rng, cfg.values, fn)
+			}
+		}
+	}
+	if cfg.replay != "" {
+		f, err := os.Open(cfg.replay)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
 
-	// amounts — слайс, в который собирается статистика по горутинам
-	amounts := make([]int64, NumOut)
-	// chOut — канал, в который будут отправляться числа из горутин `outs[i]`
-	chOut := make(chan int64, NumOut)
+		// числа из файла кончаются раньше таймаута, если -duration
+		// достаточно велик, и тогда итоги совпадают с итогами записи
+		gen = func(ctx context.Context, ch chan<- int64, fn func(int64)) {
+			malformed, err := pipeline.GeneratorFromReader(ctx, f, ch, fn)
+			if malformed > 0 {
+				log.Printf("Ошибка: в %s пропущено строк, не являющихся числами: %d\n", cfg.replay, malformed)
+			}
+			if err != nil {
+				log.Printf("Ошибка: %v\n", err)
+			}
+		}
+	}
 
-	var wg sync.WaitGroup
+	if cfg.capture != "" {
+		f, err := os.Create(cfg.capture)
+		if err != nil {
+			return err
+		}
+		rec := pipeline.NewRecorder(f)
+		opts = append(opts, pipeline.WithObserver(rec))
 
-	// 4. Собираем числа из каналов outs
-	// ...
+		// запись дописывается и файл закрывается при любом исходе run
+		defer func() {
+			ferr := rec.Flush()
+			if cerr := f.Close(); ferr == nil {
+				ferr = cerr
+			}
+			if err == nil {
+				err = ferr
+			}
+		}()
+	}
+	// newMux создаёт обработчик для HTTP-сервера с /healthz; все серверы
+	// делят один счётчик сгенерированных чисел
+	var live *metrics.Stats
+	newMux := func() *http.ServeMux {
+		if live == nil {
+			live = metrics.NewStats(cfg.workers)
+			opts = append(opts, pipeline.WithObserver(live))
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", healthHandler(ctx, func() int64 { return live.Snapshot().InputCount }))
+		return mux
+	}
 
-	go func() {
-		// ждём завершения работы всех горутин для outs
-		wg.Wait()
-		// закрываем результирующий канал
-		close(chOut)
-	}()
+	if cfg.metricsAddr != "" {
+		reg := prometheus.NewRegistry()
+		var registerer prometheus.Registerer = reg
+		if cfg.runID != "" {
+			registerer = prometheus.WrapRegistererWith(prometheus.Labels{"run_id": cfg.runID}, reg)
+		}
+		m, err := metrics.NewPrometheus(registerer)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, pipeline.WithObserver(m))
+
+		mux := newMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		stop := startServer(cfg.metricsAddr, mux)
+		defer stop()
+	}
+
+	if cfg.expvarAddr != "" {
+		opts = append(opts, pipeline.WithObserver(metrics.NewExpvar()))
+
+		mux := newMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		stop := startServer(cfg.expvarAddr, mux)
+		defer stop()
+	}
+
+	// result объявлен заранее, чтобы потоки SSE получили итоговое событие
+	// при любом выходе из run, в том числе по ошибке конвейера
+	var result pipeline.Result
+	if cfg.sseAddr != "" {
+		events := sse.NewBroadcaster(sse.DefaultBuffer)
+		opts = append(opts, pipeline.WithObserver(events))
 
-	var count int64 // количество чисел результирующего канала
-	var sum int64   // сумма чисел результирующего канала
+		mux := newMux()
+		mux.Handle("/events", events)
+		stop := startServer(cfg.sseAddr, mux)
+		defer stop()
+		// срабатывает раньше stop, поэтому сервер не ждёт открытых потоков
+		// до конца таймаута остановки
+		defer func() {
+			if err := events.Close(result); err != nil {
+				log.Printf("Ошибка: %v\n", err)
+			}
+		}()
+	}
 
-	// 5. Читаем числа из результирующего канала
-	// ...
+	if cfg.progress {
+		opts = append(opts, pipeline.WithProgress(time.Second, func(r pipeline.Result) {
+			fmt.Fprintf(os.Stderr, "Сгенерировано %d, по каналам %v\n", r.InputCount, r.PerChannel)
+		}))
+	}
 
-	fmt.Println("Количество чисел", inputCount, count)
-	fmt.Println("Сумма чисел", inputSum, sum)
-	fmt.Println("Разбивка по каналам", amounts)
+	if cfg.cpuProfile != "" {
+		stop, err := startCPUProfile(cfg.cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if serr := stop(); err == nil {
+				err = serr
+			}
+		}()
+	}
+
+	if cfg.verifyRuns > 0 {
+		if err := verifyDeterminism(ctx, cfg.verifyRuns, cfg.workers, cfg.values, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Детерминизм подтверждён: запусков %d, чисел в каждом %d\n", cfg.verifyRuns, cfg.values)
+		return nil
+	}
+
+	if cfg.values > 0 && !cfg.seedSet {
+		// генерация кончается на cfg.values числах или по таймауту, что
+		// наступит раньше; причину показывает строка «Завершено»
+		result, err = pipeline.RunBounded(ctx, cfg.workers, cfg.values, opts...)
+	} else {
+		result, err = pipeline.RunWith(ctx, cfg.workers, gen, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.memProfile != "" {
+		if err := writeMemProfile(cfg.memProfile); err != nil {
+			return err
+		}
+	}
+	return report(os.Stdout, cfg, result)
+}
+
+// deadlineContext возвращает контекст генерации, который отменяется с
+// причиной pipeline.ErrDeadline по истечении -duration или, если задан,
+// в момент -deadline.
+func deadlineContext(parent context.Context, cfg config) (context.Context, context.CancelFunc) {
+	if cfg.deadline.IsZero() {
+		return context.WithTimeoutCause(parent, cfg.duration, pipeline.ErrDeadline)
+	}
+	// при прошедшем -deadline контекст отменён сразу: генератор не
+	// отправит ни одного числа, и итоги будут нулевыми
+	return context.WithDeadlineCause(parent, cfg.deadline, pipeline.ErrDeadline)
+}
+
+// report выводит итоги result так, как задано в cfg, — в файл -output-file
+// или, если он не задан, в stdout — и сверяет их. При -quiet текстовые
+// итоги не выводятся, но сверка выполняется всегда, так что расхождение
+// по-прежнему завершает программу с ненулевым кодом.
+func report(stdout io.Writer, cfg config, result pipeline.Result) error {
+	if !cfg.quiet || cfg.output != outputText {
+		if err := writeResultTo(stdout, cfg.outputFile, cfg.output, cfg.labels, result); err != nil {
+			return err
+		}
+	}
+	if cfg.balance {
+		writeBalance(os.Stderr, result)
+	}
 
 	// проверка результатов
-	if inputSum != sum {
-		log.Fatalf("Ошибка: суммы чисел не равны: %d != %d\n", inputSum, sum)
+	return result.Verify()
+}
+
+// Приёмники чисел с выхода конвейера для флага -sink.
+const (
+	sinkAggregate = "aggregate"
+	sinkNull      = "null"
+	sinkWriter    = "writer"
+)
+
+// openSink создаёт приёмник kind; для writer числа пишутся в файл path или,
+// если path пуст, в stdout. Функция closeFile закрывает открытый файл и
+// вызывается после завершения конвейера.
+func openSink(kind, path string) (sink pipeline.Sink, closeFile func() error, err error) {
+	noop := func() error { return nil }
+	switch kind {
+	case sinkNull:
+		return pipeline.NullSink{}, noop, nil
+	case sinkWriter:
+		if path == "" {
+			return pipeline.NewWriterSink(os.Stdout), noop, nil
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pipeline.NewWriterSink(f), f.Close, nil
+	default:
+		return pipeline.NewAggregateSink(), noop, nil
 	}
-	if inputCount != count {
-		log.Fatalf("Ошибка: количество чисел не равно: %d != %d\n", inputCount, count)
+}
+
+// outputFormat возвращает формат вывода для значения -output с учётом
+// флага -pretty, который действует только на JSON.
+func outputFormat(output string, pretty bool) string {
+	if pretty && output == outputJSON {
+		return outputJSONPretty
 	}
-	for _, v := range amounts {
-		inputCount -= v
+	return output
+}
+
+// writeResultTo выводит итоги в файл path или, если path пуст, в stdout.
+// Файл закрывается и при ошибке записи; ошибка закрытия тоже возвращается,
+// так как без неё итоги могут оказаться записанными не полностью.
+func writeResultTo(stdout io.Writer, path, format string, labels pipeline.Labels, result pipeline.Result) (err error) {
+	if path == "" {
+		return writeResult(stdout, format, labels, result)
 	}
-	if inputCount != 0 {
-		log.Fatalf("Ошибка: разделение чисел по каналам неверное\n")
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return writeResult(f, format, labels, result)
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startCPUProfile начинает запись профиля процессора в файл path. Функция
+// stop останавливает профилирование и закрывает файл; её нужно вызвать и
+// тогда, когда работа завершилась ошибкой.
+func startCPUProfile(path string) (stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		return f.Close()
+	}, nil
+}
+
+// writeMemProfile записывает профиль памяти в файл path. Перед записью
+// выполняется сборка мусора, чтобы профиль отражал актуальные данные.
+func writeMemProfile(path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
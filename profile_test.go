@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// gzipMagic — начало файла профиля pprof, который сжат gzip.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// checkProfile проверяет, что path — непустой профиль pprof.
+func checkProfile(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		t.Fatalf("%s не похож на профиль pprof (%d байт)", path, len(data))
+	}
+}
+
+func TestProfilesWritten(t *testing.T) {
+	dir := t.TempDir()
+	cpu := filepath.Join(dir, "cpu.pprof")
+	mem := filepath.Join(dir, "mem.pprof")
+
+	stop, err := startCPUProfile(cpu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pipeline.RunBounded(context.Background(), 2, 10000, pipeline.WithDelay(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMemProfile(mem); err != nil {
+		t.Fatal(err)
+	}
+	checkProfile(t, cpu)
+	checkProfile(t, mem)
+}
+
+func TestStartCPUProfileBadPath(t *testing.T) {
+	if _, err := startCPUProfile(filepath.Join(t.TempDir(), "нет", "cpu.pprof")); err == nil {
+		t.Fatal("профиль в несуществующем каталоге создан без ошибки")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout — сколько ждать завершения активных запросов при
+// остановке HTTP-сервера.
+const shutdownTimeout = time.Second
+
+// startServer запускает HTTP-сервер с обработчиком handler на адресе addr и
+// возвращает функцию, которая его останавливает.
+func startServer(addr string, handler http.Handler) (stop func()) {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Ошибка HTTP-сервера %s: %v\n", addr, err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Ошибка остановки HTTP-сервера %s: %v\n", addr, err)
+		}
+	}
+}
+
+// healthHandler отвечает на проверки живости: 200 и status "ok", пока ctx не
+// отменён, и 503 и status "shutting_down" после отмены, когда генерация уже
+// остановлена. В теле ответа также передаётся количество сгенерированных
+// чисел.
+func healthHandler(ctx context.Context, generated func() int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, status := http.StatusOK, "ok"
+		if ctx.Err() != nil {
+			code, status = http.StatusServiceUnavailable, "shutting_down"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		err := json.NewEncoder(w).Encode(struct {
+			Status    string `json:"status"`
+			Generated int64  `json:"generated"`
+		}{status, generated()})
+		if err != nil {
+			log.Printf("Ошибка ответа на %s: %v\n", r.URL.Path, err)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := healthHandler(ctx, func() int64 { return 42 })
+
+	check := func(wantCode int, wantStatus string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != wantCode {
+			t.Fatalf("код ответа %d, ожидался %d", rec.Code, wantCode)
+		}
+		var body struct {
+			Status    string `json:"status"`
+			Generated int64  `json:"generated"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("тело ответа не JSON: %v", err)
+		}
+		if body.Status != wantStatus || body.Generated != 42 {
+			t.Fatalf("получено %+v, ожидалось status %q и generated 42", body, wantStatus)
+		}
+	}
+	check(http.StatusOK, "ok")
+	cancel()
+	check(http.StatusServiceUnavailable, "shutting_down")
+}
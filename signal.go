@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// notifyContext работает как signal.NotifyContext, но отменяет контекст с
+// причиной pipeline.ErrSignal, чтобы по context.Cause было видно, что
+// генерацию остановил сигнал. Функция stop прекращает приём сигналов и
+// освобождает контекст.
+func notifyContext(parent context.Context, signals ...os.Signal) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case <-ch:
+			cancel(pipeline.ErrSignal)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel(nil)
+	}
+}
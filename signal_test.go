@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// Сигнал отменяет контекст так же, как таймаут: конвейер дочитывается, и
+// итоги сходятся.
+func TestSignalStopsRunWithConsistentSummary(t *testing.T) {
+	sigCtx, stop := notifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeoutCause(sigCtx, 10*time.Second, pipeline.ErrDeadline)
+	defer cancel()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+	result, err := pipeline.Run(ctx, 4, pipeline.WithDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(context.Cause(ctx), pipeline.ErrSignal) {
+		t.Fatalf("причина остановки %v, ожидалась ErrSignal", context.Cause(ctx))
+	}
+	if result.StopCause != "signal" {
+		t.Fatalf("StopCause = %q, ожидалось signal", result.StopCause)
+	}
+	if result.InputCount == 0 {
+		t.Fatal("до сигнала не сгенерировано ни одного числа")
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatalf("итоги не сходятся: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package sse транслирует поток чисел конвейера клиентам HTTP по протоколу
+// Server-Sent Events.
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// DefaultBuffer — сколько событий по умолчанию может ждать отправки одному
+// клиенту, прежде чем новые события для него начнут отбрасываться.
+const DefaultBuffer = 256
+
+// Broadcaster — наблюдатель конвейера, рассылающий каждое число,
+// дошедшее до результирующего канала, всем подключённым клиентам как
+// событие value, а по завершении — итоговое событие summary.
+//
+// У каждого клиента своя очередь из buffer событий. Если клиент не успевает
+// их читать, новые события для него отбрасываются и учитываются в счётчике
+// Dropped, так что медленный клиент не тормозит конвейер.
+type Broadcaster struct {
+	buffer  int
+	dropped int64
+
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+	summary string // итоговое событие; непусто после Close
+}
+
+// NewBroadcaster создаёт Broadcaster с очередью из buffer событий на
+// клиента; при buffer <= 0 используется DefaultBuffer.
+func NewBroadcaster(buffer int) *Broadcaster {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broadcaster{
+		buffer:  buffer,
+		clients: make(map[chan string]struct{}),
+	}
+}
+
+// Generated ничего не делает: клиентам транслируется выход конвейера.
+func (b *Broadcaster) Generated(int64) {}
+
+// Processed рассылает число v клиентам как событие value.
+func (b *Broadcaster) Processed(_ int, v int64) {
+	b.broadcast(event("value", strconv.FormatInt(v, 10)))
+}
+
+// Dropped возвращает, сколько событий было отброшено для отстающих
+// клиентов.
+func (b *Broadcaster) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// Close рассылает итоговое событие summary с результатом r и количеством
+// отброшенных событий и завершает потоки всех клиентов. Клиенты,
+// подключившиеся после Close, сразу получают summary.
+func (b *Broadcaster) Close(r pipeline.Result) error {
+	data, err := json.Marshal(struct {
+		pipeline.Result
+		Dropped int64 `json:"dropped"`
+	}{r, b.Dropped()})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.summary = event("summary", string(data))
+	for ch := range b.clients {
+		close(ch)
+		delete(b.clients, ch)
+	}
+	return nil
+}
+
+// ServeHTTP отдаёт клиенту поток событий, пока не завершится конвейер или
+// не отключится сам клиент.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch, summary := b.subscribe()
+	if ch == nil {
+		fmt.Fprint(w, summary)
+		flusher.Flush()
+		return
+	}
+	defer b.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				// Close уже выставил summary под мьютексом до закрытия ch
+				b.mu.Lock()
+				e = b.summary
+				b.mu.Unlock()
+			}
+			if _, err := fmt.Fprint(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// subscribe регистрирует нового клиента. Если рассылка уже завершена,
+// возвращает nil и итоговое событие.
+func (b *Broadcaster) subscribe() (chan string, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.summary != "" {
+		return nil, b.summary
+	}
+	ch := make(chan string, b.buffer)
+	b.clients[ch] = struct{}{}
+	return ch, ""
+}
+
+// unsubscribe снимает клиента с рассылки, если Close ещё не сделал этого.
+func (b *Broadcaster) unsubscribe(ch chan string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, ch)
+}
+
+// broadcast кладёт событие e в очередь каждого клиента, не дожидаясь
+// освободившегося места.
+func (b *Broadcaster) broadcast(e string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- e:
+		default:
+			atomic.AddInt64(&b.dropped, 1)
+		}
+	}
+}
+
+// event форматирует событие SSE с именем name и данными data.
+func event(name, data string) string {
+	return "event: " + name + "\ndata: " + data + "\n\n"
+}
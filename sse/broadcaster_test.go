@@ -0,0 +1,144 @@
+package sse
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavel-a-borisov/go-project-sprint-9/pipeline"
+)
+
+// waitClients ждёт, пока у b не станет n подключённых клиентов.
+func waitClients(t *testing.T, b *Broadcaster, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		b.mu.Lock()
+		got := len(b.clients)
+		b.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("клиентов %d, ожидалось %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// summaryOf разбирает данные события summary в потоке stream.
+func summaryOf(t *testing.T, stream string) (r pipeline.Result, dropped int64) {
+	t.Helper()
+	_, data, ok := strings.Cut(stream, "event: summary\ndata: ")
+	if !ok {
+		t.Fatalf("в потоке нет события summary:\n%s", stream)
+	}
+	var summary struct {
+		pipeline.Result
+		Dropped int64 `json:"dropped"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &summary); err != nil {
+		t.Fatalf("данные summary не JSON: %v", err)
+	}
+	return summary.Result, summary.Dropped
+}
+
+func TestBroadcasterStreamsValuesAndSummary(t *testing.T) {
+	b := NewBroadcaster(0)
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	// заголовки уходят вместе с первым событием, поэтому запрос
+	// выполняется параллельно с рассылкой
+	type response struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(srv.URL)
+		done <- response{resp, err}
+	}()
+
+	waitClients(t, b, 1)
+	b.Generated(100) // сгенерированные числа клиентам не транслируются
+	b.Processed(0, 1)
+	b.Processed(1, 2)
+	result := pipeline.Result{InputCount: 2, InputSum: 3, OutputCount: 2, OutputSum: 3, PerChannel: []int64{1, 1}}
+	if err := b.Close(result); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	defer r.resp.Body.Close()
+	if ct := r.resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q, ожидался text/event-stream", ct)
+	}
+	// после Close сервер завершает поток, поэтому тело читается до конца
+	body, err := io.ReadAll(r.resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := string(body)
+	want := "event: value\ndata: 1\n\nevent: value\ndata: 2\n\nevent: summary\n"
+	if !strings.HasPrefix(stream, want) {
+		t.Fatalf("получен поток:\n%s\nожидалось начало:\n%s", stream, want)
+	}
+	if got, dropped := summaryOf(t, stream); !got.Equal(result) || dropped != 0 {
+		t.Fatalf("в summary итоги %+v и отброшено %d, ожидалось %+v и 0", got, dropped, result)
+	}
+	waitClients(t, b, 0)
+}
+
+func TestBroadcasterDropsForSlowClient(t *testing.T) {
+	b := NewBroadcaster(2)
+	ch, _ := b.subscribe()
+	for v := int64(1); v <= 5; v++ {
+		b.Processed(0, v)
+	}
+	if got := b.Dropped(); got != 3 {
+		t.Fatalf("отброшено %d событий, ожидалось 3", got)
+	}
+
+	// в очереди остались первые события, а после Close она закрыта
+	if err := b.Close(pipeline.Result{}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for e := range ch {
+		got = append(got, e)
+	}
+	if len(got) != 2 || got[0] != event("value", "1") || got[1] != event("value", "2") {
+		t.Fatalf("в очереди %q, ожидались события 1 и 2", got)
+	}
+}
+
+func TestBroadcasterSummaryAfterClose(t *testing.T) {
+	b := NewBroadcaster(1)
+	ch, _ := b.subscribe()
+	b.Processed(0, 1)
+	b.Processed(0, 2) // отбрасывается: очередь клиента полна
+	result := pipeline.Result{InputCount: 2, InputSum: 3, OutputCount: 2, OutputSum: 3, PerChannel: []int64{2}}
+	if err := b.Close(result); err != nil {
+		t.Fatal(err)
+	}
+	b.unsubscribe(ch)
+
+	// клиент, подключившийся после Close, сразу получает только summary
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	stream := rec.Body.String()
+	if !strings.HasPrefix(stream, "event: summary\n") {
+		t.Fatalf("получен поток:\n%s\nожидалось одно событие summary", stream)
+	}
+	if got, dropped := summaryOf(t, stream); !got.Equal(result) || dropped != 1 {
+		t.Fatalf("в summary итоги %+v и отброшено %d, ожидалось %+v и 1", got, dropped, result)
+	}
+}